@@ -112,6 +112,11 @@ func BenchmarkDifferentShardCounts(b *testing.B) {
 
 // BenchmarkMapTypes 不同Map类型的性能对比
 func BenchmarkMapTypes(b *testing.B) {
+	b.Run("NativeMap", func(b *testing.B) {
+		cm := NewNativeMap[string, int]()
+		benchmarkMapType(b, cm)
+	})
+
 	b.Run("HashMap", func(b *testing.B) {
 		cm := NewHashMap[string, int]()
 		benchmarkMapType(b, cm)
@@ -225,3 +230,117 @@ func BenchmarkVsStandardMap(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkSize 基准测试并发写入时的 Size
+func BenchmarkSize(b *testing.B) {
+	for _, exact := range []bool{false, true} {
+		b.Run(fmt.Sprintf("Exact=%v", exact), func(b *testing.B) {
+			var options []Option
+			if exact {
+				options = append(options, WithExactSize())
+			}
+			cm := New[int, int](options...)
+			for i := 0; i < 10000; i++ {
+				cm.Put(i, i)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					if i%10 == 0 {
+						cm.Put(i%10000, i)
+					} else {
+						cm.Size()
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkReadOptimized 基准测试读多写少时只读视图的效果
+func BenchmarkReadOptimized(b *testing.B) {
+	for _, optimized := range []bool{false, true} {
+		b.Run(fmt.Sprintf("ReadOptimized=%v", optimized), func(b *testing.B) {
+			var options []Option
+			if optimized {
+				options = append(options, WithReadOptimized())
+			}
+			cm := New[int, int](options...)
+			for i := 0; i < 10000; i++ {
+				cm.Put(i, i)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					cm.Get(i % 10000)
+					i++
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkNativeVsGods 内置map分片与 gods HashMap 分片的性能对比
+func BenchmarkNativeVsGods(b *testing.B) {
+	backends := []struct {
+		name string
+		new  func() *Map[int, int]
+	}{
+		{"Native", func() *Map[int, int] { return NewNativeMap[int, int]() }},
+		{"GodsHashMap", func() *Map[int, int] { return NewHashMap[int, int]() }},
+	}
+
+	for _, backend := range backends {
+		b.Run(backend.name+"/Put", func(b *testing.B) {
+			cm := backend.new()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cm.Put(i%10000, i)
+			}
+		})
+
+		b.Run(backend.name+"/Get", func(b *testing.B) {
+			cm := backend.new()
+			for i := 0; i < 10000; i++ {
+				cm.Put(i, i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cm.Get(i % 10000)
+			}
+		})
+
+		b.Run(backend.name+"/Range", func(b *testing.B) {
+			cm := backend.new()
+			for i := 0; i < 10000; i++ {
+				cm.Put(i, i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				cm.Range(func(key, value int) bool { return true })
+			}
+		})
+
+		b.Run(backend.name+"/Parallel", func(b *testing.B) {
+			cm := backend.new()
+			for i := 0; i < 10000; i++ {
+				cm.Put(i, i)
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					if i%10 == 0 {
+						cm.Put(i%10000, i)
+					} else {
+						cm.Get(i % 10000)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
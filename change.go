@@ -0,0 +1,90 @@
+package cmap
+
+import "cmp"
+
+// changeKind 变更类型
+type changeKind uint8
+
+const (
+	changeExpire changeKind = iota + 1 // 键过期
+	changeEvict                        // 键因超出容量被淘汰
+	changePut                          // 键被写入
+	changeRemove                       // 键被删除
+	changeLoad                         // 键由 GetOrLoad 等从外部加载写入，不写回 Store
+	changeClear                        // 键因 Clear 被删除，不写回 Store，订阅者只收到一个 EventClear
+)
+
+// change 分片锁内产生的变更，在释放分片锁后派发给回调
+type change[K cmp.Ordered, V any] struct {
+	kind     changeKind
+	key      K
+	old      V    // 变更前的值，过期、淘汰、删除时为被删除的值
+	value    V    // 写入的新值
+	replaced bool // old是否有效
+}
+
+// record 记录过期、淘汰变更，调用方需持有分片写锁
+func (sh *shard[K, V]) record(c change[K, V]) {
+	if sh.track || sh.watched.Load() {
+		sh.changes = append(sh.changes, c)
+	}
+}
+
+// recordWrite 记录写入、删除变更，调用方需持有分片写锁
+func (sh *shard[K, V]) recordWrite(c change[K, V]) {
+	if sh.writes || sh.watched.Load() {
+		sh.changes = append(sh.changes, c)
+	}
+}
+
+// unlock 释放分片写锁，并在锁外派发锁内产生的变更；总成本超出上限时从其他分片淘汰
+func (m *Map[K, V]) unlock(sh *shard[K, V]) {
+	if changes := m.release(sh); len(changes) > 0 {
+		m.dispatch(changes)
+	}
+	m.trim(sh)
+}
+
+// release 释放分片写锁，返回锁内产生、尚未派发给回调的变更
+func (m *Map[K, V]) release(sh *shard[K, V]) []change[K, V] {
+	if len(sh.changes) == 0 {
+		sh.mu.Unlock()
+		return nil
+	}
+
+	changes := sh.changes
+	sh.changes = nil
+	// 在锁内写入 Store 和发布事件，保证同一个键的变更按顺序处理
+	if m.writer != nil {
+		m.writer.write(changes)
+	}
+	if m.watched.Load() {
+		m.publish(changes)
+	}
+	sh.mu.Unlock()
+	return changes
+}
+
+// dispatch 派发变更
+func (m *Map[K, V]) dispatch(changes []change[K, V]) {
+	for _, c := range changes {
+		switch c.kind {
+		case changeExpire:
+			if m.onExpire != nil {
+				m.onExpire(c.key, c.old)
+			}
+		case changeEvict:
+			if m.onEvict != nil {
+				m.onEvict(c.key, c.old)
+			}
+		case changePut, changeLoad:
+			if m.onPut != nil {
+				m.onPut(c.key, c.old, c.value, c.replaced)
+			}
+		case changeRemove, changeClear:
+			if m.onRemove != nil {
+				m.onRemove(c.key, c.old)
+			}
+		}
+	}
+}
@@ -2,48 +2,389 @@ package cmap
 
 import (
 	"cmp"
+	"math"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/emirpasic/gods/v2/maps"
+	"github.com/emirpasic/gods/v2/maps/linkedhashmap"
+	"github.com/emirpasic/gods/v2/maps/treemap"
 )
 
 // Map 并发安全的Map实现
 type Map[K cmp.Ordered, V any] struct {
 	shards []shard[K, V]
 	mask   uint32
-	dirty  bool
-	mu     *sync.RWMutex // 用于保护dirty字段
-	hasher hasher[K]     // 哈希器
+	sorted bool      // 底层为TreeMap时为true，遍历结果需全局按键有序
+	order  bool      // 开启全局插入顺序时为true，遍历结果按插入顺序合并
+	hasher hasher[K] // 哈希器
 	opts   *Options
+
+	backend func() maps.Map[K, V] // 创建分片底层Map，用于 Clone，使用内置map时为nil
+
+	onExpire  func(key K, value V)                             // 键过期回调
+	onEvict   func(key K, value V)                             // 键淘汰回调
+	onPut     func(key K, oldValue, newValue V, replaced bool) // 键写入回调
+	onRemove  func(key K, value V)                             // 键删除回调
+	beforePut func(key K, value V) error                       // 写入前校验
+	equal     func(a, b V) bool                                // 值比较函数
+
+	loadMu    *sync.Mutex        // 保护loads与negatives
+	loads     map[K]*loadCall[V] // 正在进行的加载
+	negatives map[K]negative     // 加载失败的缓存
+
+	writer *storeWriter[K, V] // 后端存储写入器，未设置 WithStore 时为nil
+
+	subsMu  *sync.RWMutex                          // 保护subs
+	subs    map[*subscription[K, V]]struct{}       // 事件订阅者
+	keyed   map[K]map[*subscription[K, V]]struct{} // 按键索引的事件订阅者
+	watched *atomic.Bool                           // 是否存在订阅者，与各分片共享
+
+	stop      chan struct{}   // 关闭后台清理协程
+	closeOnce *sync.Once      // 保证Close只执行一次
+	wg        *sync.WaitGroup // 等待后台协程退出
+}
+
+// cacheLineSize CPU缓存行大小
+const cacheLineSize = 64
+
+// paddedUint64 独占缓存行的原子计数器，避免频繁写入时与相邻字段或其他分片的计数器产生伪共享
+type paddedUint64 struct {
+	_ [cacheLineSize]byte
+	atomic.Uint64
+	_ [cacheLineSize - 8]byte
+}
+
+// shardSize 分片的无锁计数，独占缓存行
+type shardSize struct {
+	_     [cacheLineSize]byte
+	count atomic.Int64 // 底层Map中的键数量，包括已过期但尚未删除的键
+	ttls  atomic.Int64 // 设置了TTL的键数量
+	next  atomic.Int64 // 不晚于分片内最早的过期时间，在此之前没有键过期
+	_     [cacheLineSize - 24]byte
+}
+
+// lower 记录新的过期时间，调用方需持有分片写锁
+func (s *shardSize) lower(deadline int64) {
+	if deadline < s.next.Load() {
+		s.next.Store(deadline)
+	}
 }
 
 // shard 分片结构
 type shard[K cmp.Ordered, V any] struct {
-	m    maps.Map[K, V]
-	mu   *sync.RWMutex
-	opts *Options
+	m      maps.Map[K, V] // 底层Map，使用内置map时为nil
+	native map[K]V        // 内置map，使用 maps.Map 时为nil
+	mu     *sync.RWMutex
+	opts   *Options
+	seq    *atomic.Uint64 // 全局插入序号生成器，未开启全局插入顺序时为nil
+	seqs   map[K]uint64   // 键首次插入时的全局序号
+
+	expires map[K]expiry // 设置了TTL的键的过期信息
+
+	capacity    int               // 分片容量上限，0表示不限制
+	policy      EvictionPolicy[K] // 淘汰策略，未设置容量和成本上限时为nil
+	maxCost     int64             // 所有分片共享的成本上限，0表示不限制
+	cost        atomic.Int64      // 分片内所有键的成本之和
+	total       *atomic.Int64     // 所有分片的成本之和，各分片共享
+	costs       map[K]int64       // 每个键的成本
+	weigh       func(K, V) int64  // 成本计算函数
+	writeOnRead bool              // 读操作是否需要更新键状态（滑动过期、淘汰策略），为true时Get需要写锁
+
+	version paddedUint64  // 分片版本号，每次修改数据时在写锁内递增，用于修改标记和乐观事务检测冲突
+	saved   atomic.Uint64 // 最近一次保存到文件或从文件加载时的版本号
+	size    shardSize     // 无锁计数，在写锁内更新
+
+	views  bool                            // 是否开启只读视图（WithReadOptimized）
+	view   atomic.Pointer[shardView[K, V]] // 最近一次生成的只读视图
+	misses paddedUint64                    // 只读视图失效后的读取次数
+
+	backend func() maps.Map[K, V] // 创建底层Map，用于写时复制，使用内置map时为nil
+	refs    *atomic.Int32         // 引用当前分片数据的快照数量，为nil表示未被快照引用
+
+	track   bool           // 是否需要记录锁内产生的过期、淘汰变更
+	writes  bool           // 是否需要记录锁内产生的写入、删除变更
+	watched *atomic.Bool   // 是否存在订阅者，存在时记录所有变更
+	clears  bool           // 清空分片时是否需要逐个记录被删除的键
+	changes []change[K, V] // 锁内产生、待释放锁后派发的变更
 }
 
-// Put 插入键值对
-func (m *Map[K, V]) Put(key K, value V) {
-	sh := m.getShard(key)
-	sh.mu.Lock()
-	sh.m.Put(key, value)
-	sh.mu.Unlock()
+// get 获取未过期的值，调用方需持有分片锁
+func (sh *shard[K, V]) get(key K) (value V, found bool) {
+	value, found = sh.find(key)
+	if found && sh.expired(key, sh.now()) {
+		var zero V
+		return zero, false
+	}
+	return
+}
 
-	m.mu.Lock()
-	m.dirty = true
-	m.mu.Unlock()
+// put 按默认TTL写入键值对，返回旧值以及键原先是否存在，调用方需持有分片写锁
+func (sh *shard[K, V]) put(key K, value V) (old V, replaced bool) {
+	return sh.putTTL(key, value, sh.opts.DefaultTTL)
 }
 
-// Get 获取值
+// putTTL 写入键值对并设置存活时间，ttl<=0表示永不过期，返回旧值以及键原先是否存在（已过期视为不存在），
+// 调用方需持有分片写锁
+func (sh *shard[K, V]) putTTL(key K, value V, ttl time.Duration) (old V, replaced bool) {
+	old, replaced = sh.set(key, value, ttl)
+	sh.recordWrite(change[K, V]{kind: changePut, key: key, old: old, value: value, replaced: replaced})
+	return
+}
+
+// set 写入键值对但不记录写入变更，用于从外部加载的值，调用方需持有分片写锁
+func (sh *shard[K, V]) set(key K, value V, ttl time.Duration) (old V, replaced bool) {
+	sh.own()
+	sh.version.Add(1)
+	now := sh.now()
+	old, replaced = sh.find(key)
+	if replaced && sh.expired(key, now) {
+		// 已过期的键按新插入处理
+		sh.dropExpired(key, old)
+		var zero V
+		old, replaced = zero, false
+	}
+	var cost int64
+	if sh.policy != nil {
+		if sh.maxCost > 0 {
+			cost = sh.weigh(key, value)
+		}
+		sh.evict(key, replaced, cost)
+	}
+	sh.store(key, value)
+	if !replaced && sh.seq != nil {
+		sh.seqs[key] = sh.seq.Add(1)
+	}
+	if ttl > 0 {
+		sh.expires[key] = expiry{deadline: now + int64(ttl), ttl: ttl}
+		sh.size.lower(now + int64(ttl))
+	} else if replaced {
+		delete(sh.expires, key)
+	}
+	if sh.maxCost > 0 {
+		sh.cost.Add(cost - sh.costs[key])
+		sh.total.Add(cost - sh.costs[key])
+		sh.costs[key] = cost
+	}
+	if sh.policy != nil {
+		if replaced {
+			sh.policy.Access(key)
+		} else {
+			sh.policy.Add(key)
+		}
+	}
+	sh.recount()
+	return
+}
+
+// remove 删除键，返回被删除的值以及键是否存在（已过期视为不存在），调用方需持有分片写锁
+func (sh *shard[K, V]) remove(key K) (old V, removed bool) {
+	old, removed = sh.find(key)
+	if removed {
+		if sh.expired(key, sh.now()) {
+			sh.dropExpired(key, old)
+			var zero V
+			return zero, false
+		}
+		sh.drop(key)
+		sh.recordWrite(change[K, V]{kind: changeRemove, key: key, old: old, replaced: true})
+	}
+	return
+}
+
+// drop 从底层Map及附加索引中删除键，调用方需持有分片写锁
+func (sh *shard[K, V]) drop(key K) {
+	sh.own()
+	sh.version.Add(1)
+	sh.erase(key)
+	if sh.seq != nil {
+		delete(sh.seqs, key)
+	}
+	delete(sh.expires, key)
+	if sh.policy != nil {
+		sh.policy.Remove(key)
+	}
+	if cost, ok := sh.costs[key]; ok {
+		sh.cost.Add(-cost)
+		sh.total.Add(-cost)
+		delete(sh.costs, key)
+	}
+	sh.recount()
+}
+
+// clear 清空分片，调用方需持有分片写锁
+func (sh *shard[K, V]) clear() {
+	if sh.clears {
+		sh.each(func(key K, value V) bool {
+			sh.changes = append(sh.changes, change[K, V]{kind: changeClear, key: key, old: value, replaced: true})
+			return true
+		})
+	}
+	sh.version.Add(1)
+	if sh.shared() {
+		// 快照仍引用当前数据，直接换成新的空数据而不是复制后再清空
+		sh.fresh()
+		sh.expires = make(map[K]expiry)
+		if sh.seq != nil {
+			sh.seqs = make(map[K]uint64)
+		}
+		sh.refs = nil
+	} else {
+		sh.wipe()
+		if sh.seq != nil {
+			clear(sh.seqs)
+		}
+		clear(sh.expires)
+	}
+	if sh.policy != nil {
+		sh.policy.Clear()
+	}
+	if sh.maxCost > 0 {
+		clear(sh.costs)
+		sh.total.Add(-sh.cost.Swap(0))
+	}
+	sh.recount()
+}
+
+// recount 更新分片的无锁计数，调用方需持有分片写锁
+func (sh *shard[K, V]) recount() {
+	sh.size.count.Store(int64(sh.entries()))
+	sh.size.ttls.Store(int64(len(sh.expires)))
+	if len(sh.expires) == 0 {
+		sh.size.next.Store(math.MaxInt64)
+	}
+}
+
+// count 返回分片内未过期的键数量
+//
+// 分片内没有设置了TTL的键，或者尚未到达最早的过期时间时直接读取原子计数，无需加锁；
+// 否则加锁排除已过期但尚未删除的键，并重新计算最早的过期时间，过期的键被删除后恢复无锁读取。
+func (sh *shard[K, V]) count() int {
+	if sh.size.ttls.Load() == 0 || sh.now() < sh.size.next.Load() {
+		return int(sh.size.count.Load())
+	}
+
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	size := sh.entries()
+	now, next := sh.now(), int64(math.MaxInt64)
+	for _, e := range sh.expires {
+		if e.deadline <= now {
+			size--
+		}
+		next = min(next, e.deadline)
+	}
+	// 读锁内没有写入，并发的读取者计算出相同的结果
+	sh.size.next.Store(next)
+	return size
+}
+
+// len 返回分片内未过期的键数量，调用方需持有分片锁
+func (sh *shard[K, V]) len() int {
+	size := sh.entries()
+	if len(sh.expires) > 0 {
+		now := sh.now()
+		for _, e := range sh.expires {
+			if e.deadline <= now {
+				size--
+			}
+		}
+	}
+	return size
+}
+
+// each 按底层Map的顺序遍历分片内未过期的键值对，fn返回false时停止并返回false，调用方需持有分片锁
+func (sh *shard[K, V]) each(fn func(key K, value V) bool) bool {
+	if len(sh.expires) > 0 {
+		now := sh.now()
+		visit := fn
+		fn = func(key K, value V) bool {
+			if sh.expired(key, now) {
+				return true
+			}
+			return visit(key, value)
+		}
+	}
+
+	return sh.scan(fn)
+}
+
+// iterate 按底层Map的顺序遍历所有键值对，fn返回false时停止并返回false
+func iterate[K cmp.Ordered, V any](m maps.Map[K, V], fn func(key K, value V) bool) bool {
+	switch m := m.(type) {
+	case *treemap.Map[K, V]:
+		it := m.Iterator()
+		for it.Next() {
+			if !fn(it.Key(), it.Value()) {
+				return false
+			}
+		}
+	case *linkedhashmap.Map[K, V]:
+		it := m.Iterator()
+		for it.Next() {
+			if !fn(it.Key(), it.Value()) {
+				return false
+			}
+		}
+	default:
+		for _, key := range m.Keys() {
+			value, _ := m.Get(key)
+			if !fn(key, value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Put 插入键值对，设置了默认TTL时按默认TTL过期
+//
+// 设置了 WithBeforePut 时，被拒绝的写入会被忽略，需要获取错误时请使用 TryPut。
+func (m *Map[K, V]) Put(key K, value V) {
+	_ = m.TryPut(key, value)
+}
+
+// Get 获取值，已过期的键视为不存在并被惰性删除
+//
+// 开启 WithSlidingExpiration 时，成功获取会按键的TTL延长其存活时间；设置了容量时会通知淘汰策略。
 func (m *Map[K, V]) Get(key K) (value V, found bool) {
 	sh := m.getShard(key)
-	sh.mu.RLock()
-	value, found = sh.m.Get(key)
-	sh.mu.RUnlock()
+	if sh.writeOnRead {
+		return m.getLocked(sh, key)
+	}
+
+	value, found, expired := sh.lookup(key)
+	if expired {
+		m.expire(sh, key)
+		var zero V
+		return zero, false
+	}
+	return
+}
+
+// getLocked 在写锁内获取值，并更新键的滑动过期时间和淘汰策略状态
+func (m *Map[K, V]) getLocked(sh *shard[K, V], key K) (value V, found bool) {
+	sh.mu.Lock()
+	value, found = sh.find(key)
+	if found {
+		now := sh.now()
+		if sh.expired(key, now) {
+			sh.dropExpired(key, value)
+			var zero V
+			value, found = zero, false
+		} else {
+			if sh.opts.SlidingExpiration {
+				sh.slide(key, now)
+			}
+			if sh.policy != nil {
+				sh.policy.Access(key)
+			}
+		}
+	}
+	m.unlock(sh)
 	return
 }
 
@@ -51,26 +392,17 @@ func (m *Map[K, V]) Get(key K) (value V, found bool) {
 func (m *Map[K, V]) Remove(key K) {
 	sh := m.getShard(key)
 	sh.mu.Lock()
-	_, ok := sh.m.Get(key)
-	if ok {
-		sh.m.Remove(key)
-	}
-	sh.mu.Unlock()
-
-	if ok {
-		m.mu.Lock()
-		m.dirty = true
-		m.mu.Unlock()
-	}
+	sh.remove(key)
+	m.unlock(sh)
 }
 
-// Empty 检查是否为空
+// Empty 检查是否为空，计数方式与 Size 相同
 func (m *Map[K, V]) Empty() bool {
+	if m.opts.ExactSize {
+		return m.exactSize() == 0
+	}
 	for i := range m.shards {
-		m.shards[i].mu.RLock()
-		empty := m.shards[i].m.Empty()
-		m.shards[i].mu.RUnlock()
-		if !empty {
+		if m.shards[i].count() != 0 {
 			return false
 		}
 	}
@@ -78,48 +410,94 @@ func (m *Map[K, V]) Empty() bool {
 }
 
 // Size 获取大小
+//
+// 默认读取各分片的原子计数，不获取分片锁，并发写入时结果不是某一时刻的一致数量；
+// 分片内有已过期但尚未删除的键时，需要获取该分片的读锁并遍历其设置了TTL的键（O(n)），
+// 直到这些键被后台清理（见 WithJanitor）或访问时删除后才恢复无锁的 O(1) 读取。
+// 开启 WithExactSize 时同时锁定所有分片，返回一致的数量。
 func (m *Map[K, V]) Size() int {
+	if m.opts.ExactSize {
+		return m.exactSize()
+	}
 	size := 0
 	for i := range m.shards {
-		m.shards[i].mu.RLock()
-		size += m.shards[i].m.Size()
-		m.shards[i].mu.RUnlock()
+		size += m.shards[i].count()
+	}
+	return size
+}
+
+// exactSize 同时持有所有分片的读锁计算数量
+func (m *Map[K, V]) exactSize() int {
+	m.rlockAll()
+	defer m.runlockAll()
+
+	size := 0
+	for i := range m.shards {
+		size += m.shards[i].len()
 	}
 	return size
 }
 
 // Clear 清空映射
+//
+// 清空期间持有所有分片的写锁，EventClear 在释放锁之前发布，订阅者不会在 EventClear 之前收到清空之后的写入。
 func (m *Map[K, V]) Clear() {
 	for i := range m.shards {
 		m.shards[i].mu.Lock()
-		m.shards[i].m.Clear()
-		m.shards[i].mu.Unlock()
+		m.shards[i].clear()
+	}
+	if m.watched.Load() {
+		m.broadcast(Event[K, V]{Op: EventClear})
 	}
 
-	m.mu.Lock()
-	m.dirty = true
-	m.mu.Unlock()
+	var changes []change[K, V]
+	for i := range m.shards {
+		changes = append(changes, m.release(&m.shards[i])...)
+	}
+	m.dispatch(changes)
 }
 
-// Keys 获取所有键
+// Keys 获取所有键，TreeMap 按键全局有序，开启 WithInsertionOrder 时按插入顺序
 func (m *Map[K, V]) Keys() []K {
+	if m.merged() {
+		items := m.items()
+		keys := make([]K, len(items))
+		for i, item := range items {
+			keys[i] = item.Key
+		}
+		return keys
+	}
+
 	var keys []K
 	for i := range m.shards {
 		m.shards[i].mu.RLock()
-		shardKeys := m.shards[i].m.Keys()
-		keys = append(keys, shardKeys...)
+		m.shards[i].each(func(key K, _ V) bool {
+			keys = append(keys, key)
+			return true
+		})
 		m.shards[i].mu.RUnlock()
 	}
 	return keys
 }
 
-// Values 获取所有值
+// Values 获取所有值，顺序与 Keys 一致
 func (m *Map[K, V]) Values() []V {
+	if m.merged() {
+		items := m.items()
+		values := make([]V, len(items))
+		for i, item := range items {
+			values[i] = item.Value
+		}
+		return values
+	}
+
 	var values []V
 	for i := range m.shards {
 		m.shards[i].mu.RLock()
-		shardValues := m.shards[i].m.Values()
-		values = append(values, shardValues...)
+		m.shards[i].each(func(_ K, value V) bool {
+			values = append(values, value)
+			return true
+		})
 		m.shards[i].mu.RUnlock()
 	}
 	return values
@@ -130,13 +508,14 @@ func (m *Map[K, V]) String() string {
 	var b strings.Builder
 	b.WriteString("CMap:\n")
 
-	for i, sh := range m.shards {
+	for i := range m.shards {
+		sh := &m.shards[i]
 		sh.mu.RLock()
-		if !sh.m.Empty() {
+		if sh.entries() > 0 {
 			b.WriteString("- Shard ")
 			b.WriteString(strconv.Itoa(i))
 			b.WriteString(": ")
-			b.WriteString(sh.m.String())
+			b.WriteString(sh.describe())
 			b.WriteString("\n")
 		}
 		sh.mu.RUnlock()
@@ -145,15 +524,43 @@ func (m *Map[K, V]) String() string {
 	return b.String()
 }
 
-// IsDirty 检查映射是否被修改过
-func (m *Map[K, V]) IsDirty() (dirty bool) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// IsDirty 检查映射自上次保存到文件或从文件加载后是否被修改过
+func (m *Map[K, V]) IsDirty() bool {
+	return len(m.changed(m.versions())) > 0
+}
 
-	dirty = m.dirty
+// versions 返回各分片当前的版本号，需要一致的结果时调用方应持有所有分片的锁
+func (m *Map[K, V]) versions() []uint64 {
+	versions := make([]uint64, len(m.shards))
+	for i := range m.shards {
+		versions[i] = m.shards[i].version.Load()
+	}
+	return versions
+}
+
+// changed 返回版本号与上次保存时不同的分片下标
+func (m *Map[K, V]) changed(versions []uint64) (shards []int) {
+	for i, version := range versions {
+		if version != m.shards[i].saved.Load() {
+			shards = append(shards, i)
+		}
+	}
 	return
 }
 
+// markSaved 记录各分片已保存的版本号，已记录更新的版本号时保持不变
+func (m *Map[K, V]) markSaved(versions []uint64) {
+	for i, version := range versions {
+		saved := &m.shards[i].saved
+		for {
+			old := saved.Load()
+			if old >= version || saved.CompareAndSwap(old, version) {
+				break
+			}
+		}
+	}
+}
+
 // getShard 获取键对应的分片
 func (m *Map[K, V]) getShard(key K) *shard[K, V] {
 	hash := m.hasher.Hash(key)
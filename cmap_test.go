@@ -1,6 +1,7 @@
 package cmap
 
 import (
+	"strconv"
 	"sync"
 	"testing"
 )
@@ -182,3 +183,44 @@ func TestMapEdgeCases(t *testing.T) {
 		t.Errorf("Overwrite test failed, got %v, want 200", val)
 	}
 }
+
+// TestMapSizeCounters 测试无锁计数与底层数据一致
+func TestMapSizeCounters(t *testing.T) {
+	for _, exact := range []bool{false, true} {
+		var options []Option
+		if exact {
+			options = append(options, WithExactSize())
+		}
+		m := NewStringHashMap[int](append(options, WithShardCount(8), WithCapacity(400))...)
+
+		var wg sync.WaitGroup
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < 200; i++ {
+					key := "key" + strconv.Itoa(w*100+i)
+					m.Put(key, i)
+					if i%3 == 0 {
+						m.Remove(key)
+					}
+					m.Size()
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		physical := 0
+		for i := range m.shards {
+			physical += m.shards[i].m.Size()
+		}
+		if m.Size() != physical {
+			t.Errorf("exact=%v: Size got %d, want %d", exact, m.Size(), physical)
+		}
+
+		m.Clear()
+		if m.Size() != 0 || !m.Empty() {
+			t.Errorf("exact=%v: Size after Clear got %d, want 0", exact, m.Size())
+		}
+	}
+}
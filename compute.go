@@ -0,0 +1,68 @@
+package cmap
+
+// Compute 在分片写锁内原子地计算键的新值
+//
+// fn 接收当前值及其是否存在，返回新值以及是否保留该键：keep 为 false 时删除该键（若存在）。
+// 返回计算后的值以及该键在计算后是否存在。fn 在持有分片锁期间执行，不得再访问当前映射，否则会死锁。
+// WithBeforePut 拒绝新值时映射保持不变，返回原值及其是否存在。fn 发生 panic 时释放分片锁后继续向上传播，映射保持不变。
+func (m *Map[K, V]) Compute(key K, fn func(oldValue V, found bool) (newValue V, keep bool)) (value V, ok bool) {
+	sh := m.getShard(key)
+	sh.mu.Lock()
+	defer m.unlock(sh) // fn panic 时同样释放分片锁
+	oldValue, found := sh.get(key)
+	newValue, keep := fn(oldValue, found)
+	if keep && m.check(key, newValue) != nil {
+		value, ok = oldValue, found
+	} else if keep {
+		sh.put(key, newValue)
+		value, ok = newValue, true
+	} else if found {
+		sh.remove(key)
+	}
+	return
+}
+
+// ComputeIfAbsent 键不存在时原子地计算并插入值，返回键当前对应的值
+//
+// 键已存在时不会调用 fn。fn 在持有分片锁期间执行，不得再访问当前映射。
+// WithBeforePut 拒绝计算结果时不写入并返回零值。
+func (m *Map[K, V]) ComputeIfAbsent(key K, fn func(key K) V) V {
+	sh := m.getShard(key)
+	sh.mu.Lock()
+	defer m.unlock(sh)
+	value, found := sh.get(key)
+	if !found {
+		value = fn(key)
+		if m.check(key, value) != nil {
+			var zero V
+			value = zero
+		} else {
+			sh.put(key, value)
+		}
+	}
+	return value
+}
+
+// ComputeIfPresent 键存在时原子地计算新值
+//
+// fn 返回新值以及是否保留该键：keep 为 false 时删除该键。键不存在时不会调用 fn。
+// 返回计算后的值以及该键在计算后是否存在。fn 在持有分片锁期间执行，不得再访问当前映射。
+// WithBeforePut 拒绝新值时映射保持不变，返回原值。
+func (m *Map[K, V]) ComputeIfPresent(key K, fn func(key K, oldValue V) (newValue V, keep bool)) (value V, ok bool) {
+	sh := m.getShard(key)
+	sh.mu.Lock()
+	defer m.unlock(sh)
+	oldValue, found := sh.get(key)
+	if found {
+		newValue, keep := fn(key, oldValue)
+		if keep && m.check(key, newValue) != nil {
+			value, ok = oldValue, true
+		} else if keep {
+			sh.put(key, newValue)
+			value, ok = newValue, true
+		} else {
+			sh.remove(key)
+		}
+	}
+	return
+}
@@ -0,0 +1,184 @@
+package cmap
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestCompute 测试Compute
+func TestCompute(t *testing.T) {
+	m := NewStringHashMap[int]()
+
+	// 键不存在时插入
+	val, ok := m.Compute("counter", func(old int, found bool) (int, bool) {
+		if found {
+			t.Error("Compute should report missing key as not found")
+		}
+		return old + 1, true
+	})
+	if !ok || val != 1 {
+		t.Errorf("Compute insert failed, got (%v, %v), want (1, true)", val, ok)
+	}
+
+	// 键存在时更新
+	val, ok = m.Compute("counter", func(old int, found bool) (int, bool) {
+		return old + 1, true
+	})
+	if !ok || val != 2 {
+		t.Errorf("Compute update failed, got (%v, %v), want (2, true)", val, ok)
+	}
+
+	// keep为false时删除
+	val, ok = m.Compute("counter", func(old int, found bool) (int, bool) {
+		return 0, false
+	})
+	if ok || val != 0 {
+		t.Errorf("Compute delete failed, got (%v, %v), want (0, false)", val, ok)
+	}
+	if _, found := m.Get("counter"); found {
+		t.Error("Compute should remove key when keep is false")
+	}
+}
+
+// TestComputeDirty 测试Compute对修改标记的影响
+func TestComputeDirty(t *testing.T) {
+	m := NewStringHashMap[int]()
+
+	m.Compute("missing", func(old int, found bool) (int, bool) {
+		return 0, false
+	})
+	if m.IsDirty() {
+		t.Error("Compute without mutation should not mark map dirty")
+	}
+
+	m.ComputeIfPresent("missing", func(key string, old int) (int, bool) {
+		return old, true
+	})
+	if m.IsDirty() {
+		t.Error("ComputeIfPresent on missing key should not mark map dirty")
+	}
+
+	m.ComputeIfAbsent("key", func(key string) int { return 1 })
+	if !m.IsDirty() {
+		t.Error("ComputeIfAbsent insert should mark map dirty")
+	}
+}
+
+// TestComputeIfAbsent 测试ComputeIfAbsent
+func TestComputeIfAbsent(t *testing.T) {
+	m := NewStringHashMap[int]()
+
+	calls := 0
+	val := m.ComputeIfAbsent("key", func(key string) int {
+		calls++
+		return 10
+	})
+	if val != 10 {
+		t.Errorf("ComputeIfAbsent returned %v, want 10", val)
+	}
+
+	val = m.ComputeIfAbsent("key", func(key string) int {
+		calls++
+		return 20
+	})
+	if val != 10 {
+		t.Errorf("ComputeIfAbsent should keep existing value, got %v, want 10", val)
+	}
+	if calls != 1 {
+		t.Errorf("ComputeIfAbsent should call fn once, got %d calls", calls)
+	}
+}
+
+// TestComputeIfPresent 测试ComputeIfPresent
+func TestComputeIfPresent(t *testing.T) {
+	m := NewStringHashMap[int]()
+
+	_, ok := m.ComputeIfPresent("key", func(key string, old int) (int, bool) {
+		t.Error("ComputeIfPresent should not call fn for missing key")
+		return 0, true
+	})
+	if ok {
+		t.Error("ComputeIfPresent should return false for missing key")
+	}
+
+	m.Put("key", 5)
+	val, ok := m.ComputeIfPresent("key", func(key string, old int) (int, bool) {
+		return old * 2, true
+	})
+	if !ok || val != 10 {
+		t.Errorf("ComputeIfPresent update failed, got (%v, %v), want (10, true)", val, ok)
+	}
+
+	_, ok = m.ComputeIfPresent("key", func(key string, old int) (int, bool) {
+		return 0, false
+	})
+	if ok {
+		t.Error("ComputeIfPresent should report removed key as absent")
+	}
+	if _, found := m.Get("key"); found {
+		t.Error("ComputeIfPresent should remove key when keep is false")
+	}
+}
+
+// TestComputeConcurrent 测试Compute的并发原子性
+func TestComputeConcurrent(t *testing.T) {
+	m := NewStringHashMap[[]int]()
+	const goroutines = 50
+	const operations = 200
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < operations; j++ {
+				m.Compute("list", func(old []int, found bool) ([]int, bool) {
+					return append(old, id), true
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	list, _ := m.Get("list")
+	if len(list) != goroutines*operations {
+		t.Errorf("Concurrent Compute lost updates, got %d, want %d", len(list), goroutines*operations)
+	}
+}
+
+// TestComputePanic 测试回调 panic 后释放分片锁
+func TestComputePanic(t *testing.T) {
+	m := NewStringHashMap[int](WithShardCount(1))
+	m.Put("key", 1)
+
+	calls := []func(){
+		func() { m.Compute("key", func(int, bool) (int, bool) { panic("boom") }) },
+		func() { m.ComputeIfAbsent("missing", func(string) int { panic("boom") }) },
+		func() { m.ComputeIfPresent("key", func(string, int) (int, bool) { panic("boom") }) },
+	}
+	for i, call := range calls {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Call %d: panic should propagate", i)
+				}
+			}()
+			call()
+		}()
+
+		done := make(chan struct{})
+		go func() {
+			m.Put("other", i)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Call %d: shard lock should be released after panic", i)
+		}
+	}
+	if value, _ := m.Get("key"); value != 1 {
+		t.Errorf("Panicking callback should not change value, got %d", value)
+	}
+}
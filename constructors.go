@@ -2,8 +2,10 @@ package cmap
 
 import (
 	"cmp"
+	"math"
 	"runtime"
 	"sync"
+	"sync/atomic"
 
 	"github.com/emirpasic/gods/v2/maps"
 	"github.com/emirpasic/gods/v2/maps/hashmap"
@@ -13,9 +15,16 @@ import (
 
 // ---------------------------------------------------------------------------------------------------------------------
 
-// New 创建默认的并发映射（使用HashMap作为底层实现）
+// New 创建默认的并发映射（使用Go内置map作为底层实现，见 NewNativeMap）
 func New[K cmp.Ordered, V any](options ...Option) *Map[K, V] {
-	return NewHashMap[K, V](options...)
+	return NewNativeMap[K, V](options...)
+}
+
+// NewNativeMap 创建直接使用Go内置map作为分片底层实现的并发映射
+//
+// 与 NewHashMap 相同，遍历顺序不固定，但读写时不经过 maps.Map 接口，开销更小。
+func NewNativeMap[K cmp.Ordered, V any](options ...Option) *Map[K, V] {
+	return createMap[K, V](nil, options...)
 }
 
 // ---------------------------------------------------------------------------------------------------------------------
@@ -124,25 +133,121 @@ func createMap[K cmp.Ordered, V any](
 	for _, option := range options {
 		option(opts)
 	}
+	return newMap(createUnderlyingMap, opts)
+}
+
+// newMap 按已应用的配置创建映射，createUnderlyingMap 为nil时分片使用Go内置map
+func newMap[K cmp.Ordered, V any](createUnderlyingMap func() maps.Map[K, V], opts *Options) *Map[K, V] {
+	var seq *atomic.Uint64
+	if opts.InsertionOrder {
+		seq = &atomic.Uint64{}
+	}
 
 	shardCount := roundUpToPowerOf2(opts.ShardCount)
+	if opts.Capacity > 0 {
+		// 分片数量不超过容量，保证每个分片至少能容纳一个键
+		for int(shardCount) > opts.Capacity {
+			shardCount >>= 1
+		}
+	}
+	// 成本上限由所有分片共享，不按分片均分
+	var total *atomic.Int64
+	if opts.MaxCost > 0 {
+		total = &atomic.Int64{}
+	}
+	evictable := opts.Capacity > 0 || opts.MaxCost > 0
+
+	newPolicy := evictionPolicy[K](opts.EvictionPolicy)
+	weigh := weigher[K, V](opts.Weigher)
+
+	watched := &atomic.Bool{}
 	shards := make([]shard[K, V], shardCount)
 	for i := range shards {
+		// 容量的余数分给前几个分片，各分片容量之和恰好等于总容量
+		shardCapacity := 0
+		if opts.Capacity > 0 {
+			shardCapacity = opts.Capacity / int(shardCount)
+			if i < opts.Capacity%int(shardCount) {
+				shardCapacity++
+			}
+		}
 		shards[i] = shard[K, V]{
-			m:    createUnderlyingMap(),
 			mu:   &sync.RWMutex{},
 			opts: opts,
+			seq:  seq,
+
+			expires: make(map[K]expiry),
+			backend: createUnderlyingMap,
+
+			capacity:    shardCapacity,
+			maxCost:     opts.MaxCost,
+			total:       total,
+			writeOnRead: opts.SlidingExpiration || evictable,
+			views:       opts.ReadOptimized && !opts.SlidingExpiration && !evictable,
+
+			track:   opts.OnExpire != nil || opts.OnEvict != nil,
+			writes:  opts.Store != nil || opts.OnPut != nil || opts.OnRemove != nil,
+			clears:  opts.OnRemove != nil,
+			watched: watched,
+		}
+		shards[i].size.next.Store(math.MaxInt64)
+		if createUnderlyingMap != nil {
+			shards[i].m = createUnderlyingMap()
+		} else {
+			shards[i].native = make(map[K]V)
+		}
+		if seq != nil {
+			shards[i].seqs = make(map[K]uint64)
+		}
+		if evictable {
+			shards[i].policy = newPolicy(shardCapacity)
+		}
+		if opts.MaxCost > 0 {
+			shards[i].costs = make(map[K]int64)
+			shards[i].weigh = weigh
 		}
 	}
 
-	return &Map[K, V]{
+	m := &Map[K, V]{
 		shards: shards,
 		mask:   shardCount - 1,
-		dirty:  false,
-		mu:     &sync.RWMutex{},
+		sorted: shards[0].sorted(),
+		order:  opts.InsertionOrder,
 		hasher: getHasher[K](),
 		opts:   opts,
+
+		backend: createUnderlyingMap,
+
+		onExpire:  hook[func(K, V)](opts.OnExpire),
+		onEvict:   hook[func(K, V)](opts.OnEvict),
+		onPut:     hook[func(K, V, V, bool)](opts.OnPut),
+		onRemove:  hook[func(K, V)](opts.OnRemove),
+		beforePut: hook[func(K, V) error](opts.BeforePut),
+		equal:     equality[V](opts.Equal),
+
+		loadMu:    &sync.Mutex{},
+		loads:     make(map[K]*loadCall[V]),
+		negatives: make(map[K]negative),
+
+		subsMu:  &sync.RWMutex{},
+		subs:    make(map[*subscription[K, V]]struct{}),
+		keyed:   make(map[K]map[*subscription[K, V]]struct{}),
+		watched: watched,
+
+		stop:      make(chan struct{}),
+		closeOnce: &sync.Once{},
+		wg:        &sync.WaitGroup{},
+	}
+	if store := hook[Store[K, V]](opts.Store); store != nil {
+		m.writer = newStoreWriter(store, opts)
+		if opts.WriteBehind {
+			m.startFlusher(opts.WriteBehindInterval)
+		}
+	}
+	if opts.JanitorInterval > 0 {
+		m.startJanitor(opts.JanitorInterval)
 	}
+	return m
 }
 
 // ---------------------------------------------------------------------------------------------------------------------
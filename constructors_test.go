@@ -268,3 +268,37 @@ func TestNewFunction(t *testing.T) {
 		}
 	})
 }
+
+// TestNativeMap 测试内置map分片
+func TestNativeMap(t *testing.T) {
+	cm := New[string, int](WithShardCount(4), WithCopyOnWrite())
+	if cm.shards[0].native == nil || cm.shards[0].m != nil {
+		t.Fatal("New should use native map shards")
+	}
+
+	cm.PutAll(map[string]int{"a": 1, "b": 2, "c": 3})
+	cm.Remove("c")
+	if cm.Size() != 2 {
+		t.Errorf("Size got %d, want 2", cm.Size())
+	}
+
+	// 写时复制快照与克隆都不受之后修改的影响
+	s := cm.Snapshot()
+	defer s.Release()
+	c := cm.Clone()
+	defer func() { _ = c.Close() }()
+	cm.Put("a", 100)
+	cm.Clear()
+	if value, _ := s.Get("a"); value != 1 || s.Size() != 2 {
+		t.Errorf("Snapshot got a=%d size=%d, want a=1 size=2", value, s.Size())
+	}
+	if c.shards[0].native == nil {
+		t.Error("Clone should use native map shards")
+	}
+	if value, _ := c.Get("a"); value != 1 || c.Size() != 2 {
+		t.Errorf("Clone got a=%d size=%d, want a=1 size=2", value, c.Size())
+	}
+	if !cm.Empty() {
+		t.Error("Cleared map should be empty")
+	}
+}
@@ -0,0 +1,156 @@
+package cmap
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestMaxCost 测试成本上限
+func TestMaxCost(t *testing.T) {
+	var evicted []string
+	m := NewStringHashMap[string](
+		WithShardCount(1),
+		WithMaxCost(100),
+		WithWeigher(func(key string, value string) int64 { return int64(len(value)) }),
+		WithOnEvict(func(key string, value string) { evicted = append(evicted, key) }),
+	)
+
+	m.Put("a", strings.Repeat("x", 40))
+	m.Put("b", strings.Repeat("x", 40))
+	if m.Cost() != 80 {
+		t.Errorf("Cost got %d, want 80", m.Cost())
+	}
+
+	// 超出上限，淘汰最近最少使用的a
+	m.Put("c", strings.Repeat("x", 30))
+	if m.Cost() != 70 {
+		t.Errorf("Cost after eviction got %d, want 70", m.Cost())
+	}
+	if len(evicted) != 1 || evicted[0] != "a" {
+		t.Errorf("Evicted %v, want [a]", evicted)
+	}
+
+	// 更新已存在的键按新成本计算
+	m.Put("b", strings.Repeat("x", 10))
+	if m.Cost() != 40 {
+		t.Errorf("Cost after update got %d, want 40", m.Cost())
+	}
+
+	m.Remove("c")
+	if m.Cost() != 10 {
+		t.Errorf("Cost after remove got %d, want 10", m.Cost())
+	}
+
+	m.Clear()
+	if m.Cost() != 0 {
+		t.Errorf("Cost after clear got %d, want 0", m.Cost())
+	}
+}
+
+// TestMaxCostOversizedEntry 测试成本超过上限的单个键
+func TestMaxCostOversizedEntry(t *testing.T) {
+	m := NewStringHashMap[int](
+		WithShardCount(1),
+		WithMaxCost(10),
+		WithWeigher(func(key string, value int) int64 { return int64(value) }),
+	)
+	m.Put("a", 3)
+	m.Put("b", 3)
+	m.Put("huge", 50)
+
+	if m.Size() != 1 {
+		t.Errorf("Oversized entry should evict all other keys, size %d", m.Size())
+	}
+	if _, ok := m.Get("huge"); !ok {
+		t.Error("Oversized entry itself should be kept")
+	}
+
+	// 更新为超限的值时不会淘汰自身
+	m.Put("huge", 60)
+	if value, ok := m.Get("huge"); !ok || value != 60 {
+		t.Errorf("Updated oversized entry got (%v, %v), want (60, true)", value, ok)
+	}
+	if m.Cost() != 60 {
+		t.Errorf("Cost got %d, want 60", m.Cost())
+	}
+}
+
+// TestMaxCostDefaultShards 测试默认分片数量下总成本不超过上限
+func TestMaxCostDefaultShards(t *testing.T) {
+	m := NewStringHashMap[string](
+		WithMaxCost(1000),
+		WithWeigher(func(key string, value string) int64 { return int64(len(value)) }),
+	)
+	for i := 0; i < 50; i++ {
+		m.Put(strconv.Itoa(i), strings.Repeat("x", 200))
+		if m.Cost() > 1000 {
+			t.Fatalf("Cost after put %d got %d, exceeds 1000", i, m.Cost())
+		}
+	}
+	if m.Size() != 5 || m.Cost() != 1000 {
+		t.Errorf("Size got %d, cost got %d, want 5 and 1000", m.Size(), m.Cost())
+	}
+	if _, ok := m.Get("49"); !ok {
+		t.Error("Latest key should be kept")
+	}
+
+	// 并发写入不同分片后总成本同样不超过上限
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				m.Put(strconv.Itoa(w*1000+i), strings.Repeat("x", 10+i%190))
+			}
+		}(w)
+	}
+	wg.Wait()
+	if m.Cost() > 1000 {
+		t.Errorf("Cost after concurrent puts got %d, exceeds 1000", m.Cost())
+	}
+}
+
+// TestMaxCostDefaultWeigher 测试默认成本计算
+func TestMaxCostDefaultWeigher(t *testing.T) {
+	m := NewIntHashMap[int](WithShardCount(2), WithMaxCost(10))
+	for i := 0; i < 100; i++ {
+		m.Put(i, i)
+	}
+	if m.Size() > 10 {
+		t.Errorf("Each key should cost 1, size %d exceeds 10", m.Size())
+	}
+	if m.Cost() != int64(m.Size()) {
+		t.Errorf("Cost %d should equal size %d", m.Cost(), m.Size())
+	}
+}
+
+// TestMaxCostWithCapacity 测试成本上限与容量同时生效
+func TestMaxCostWithCapacity(t *testing.T) {
+	m := NewIntHashMap[int](
+		WithShardCount(1),
+		WithCapacity(5),
+		WithMaxCost(1000),
+		WithWeigher(func(key int, value int) int64 { return 10 }),
+	)
+	for i := 0; i < 20; i++ {
+		m.Put(i, i)
+	}
+	if m.Size() != 5 {
+		t.Errorf("Capacity should cap size at 5, got %d", m.Size())
+	}
+	if m.Cost() != 50 {
+		t.Errorf("Cost got %d, want 50", m.Cost())
+	}
+}
+
+// TestCostWithoutLimit 测试未设置成本上限
+func TestCostWithoutLimit(t *testing.T) {
+	m := NewStringHashMap[int]()
+	m.Put("a", 1)
+	if m.Cost() != 0 {
+		t.Errorf("Cost without WithMaxCost should be 0, got %d", m.Cost())
+	}
+}
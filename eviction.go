@@ -0,0 +1,463 @@
+package cmap
+
+import (
+	"cmp"
+	"container/list"
+)
+
+// EvictionPolicy 淘汰策略，决定分片超出容量时淘汰哪个键
+//
+// 每个分片持有独立的策略实例，所有方法都在分片写锁内调用，实现无需自行加锁。
+type EvictionPolicy[K comparable] interface {
+	// Add 记录新写入的键
+	Add(key K)
+	// Access 记录键被读取或更新
+	Access(key K)
+	// Remove 键被删除或过期，不再需要跟踪
+	Remove(key K)
+	// Evict 选择并移除一个待淘汰的键，没有可淘汰的键时ok为false；选中正在写入的键时会通过 Add 重新加入该键
+	Evict() (key K, ok bool)
+	// Clear 清空所有状态
+	Clear()
+}
+
+// evictExcepter 内置淘汰策略实现的可选接口，选择淘汰key以外的键，key的状态保持不变
+type evictExcepter[K comparable] interface {
+	evictExcept(key K) (victim K, ok bool)
+}
+
+// EvictionPolicyFactory 按分片容量创建淘汰策略
+type EvictionPolicyFactory[K comparable] func(capacity int) EvictionPolicy[K]
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// evict 为即将写入的key腾出空间，按淘汰策略淘汰其他键，直到分片的键数量与成本都不超过上限，
+// 调用方需持有分片写锁
+//
+// replaced 表示key已存在（更新不会增加键数量），cost 为key写入后的成本。
+func (sh *shard[K, V]) evict(key K, replaced bool, cost int64) {
+	// 先删除已过期但尚未清理的键，避免为它们淘汰未过期的键；尚未到达最早的过期时间时无需遍历
+	if sh.overflow(key, replaced, cost) && sh.now() >= sh.size.next.Load() {
+		sh.sweep()
+	}
+
+	excepter, _ := sh.policy.(evictExcepter[K])
+	skipped := false
+	for sh.overflow(key, replaced, cost) {
+		var victim K
+		var ok bool
+		if excepter != nil {
+			victim, ok = excepter.evictExcept(key)
+		} else {
+			victim, ok = sh.policy.Evict()
+		}
+		if !ok {
+			break
+		}
+		if victim == key {
+			// 正在写入的键不会被淘汰，自定义策略只能重新加入该键
+			skipped = true
+			continue
+		}
+		sh.discard(victim)
+	}
+	if skipped {
+		sh.policy.Add(key)
+	}
+}
+
+// overflow 检查写入key后分片是否超出容量，或所有分片的总成本是否超出上限，调用方需持有分片锁
+func (sh *shard[K, V]) overflow(key K, replaced bool, cost int64) bool {
+	if !replaced && sh.capacity > 0 && sh.entries() >= sh.capacity {
+		return true
+	}
+	return sh.maxCost > 0 && sh.total.Load()-sh.costs[key]+cost > sh.maxCost
+}
+
+// shed 按淘汰策略淘汰分片内的键，直到所有分片的总成本不超过上限或分片为空，调用方需持有分片写锁
+func (sh *shard[K, V]) shed() {
+	for sh.total.Load() > sh.maxCost {
+		victim, ok := sh.policy.Evict()
+		if !ok {
+			return
+		}
+		sh.discard(victim)
+	}
+}
+
+// discard 删除被淘汰的键并记录淘汰变更，调用方需持有分片写锁
+func (sh *shard[K, V]) discard(victim K) {
+	value, _ := sh.find(victim)
+	sh.drop(victim)
+	sh.record(change[K, V]{kind: changeEvict, key: victim, old: value, replaced: true})
+}
+
+// trim 总成本仍超出上限时依次从其他分片淘汰键，调用方不得持有任何分片锁
+//
+// 写入时先在所在分片内淘汰，分片内没有其他键可淘汰时总成本仍可能超出上限，此时从 skip 之后的分片开始依次淘汰；
+// skip 为nil时所有分片都参与淘汰。
+func (m *Map[K, V]) trim(skip *shard[K, V]) {
+	total := m.shards[0].total
+	if total == nil || total.Load() <= m.opts.MaxCost {
+		return
+	}
+
+	start := 0
+	for i := range m.shards {
+		if &m.shards[i] == skip {
+			start = i + 1
+			break
+		}
+	}
+	for n := range m.shards {
+		sh := &m.shards[(start+n)%len(m.shards)]
+		if sh == skip {
+			continue
+		}
+		if total.Load() <= m.opts.MaxCost {
+			return
+		}
+		sh.mu.Lock()
+		sh.shed()
+		if changes := m.release(sh); len(changes) > 0 {
+			m.dispatch(changes)
+		}
+	}
+}
+
+// Cost 返回当前所有键的成本之和，未设置 WithMaxCost 时为0
+func (m *Map[K, V]) Cost() int64 {
+	if total := m.shards[0].total; total != nil {
+		return total.Load()
+	}
+	return 0
+}
+
+// weigher 从Options中取出成本计算函数，未设置时每个键的成本为1
+func weigher[K cmp.Ordered, V any](v any) func(K, V) int64 {
+	if fn := hook[func(K, V) int64](v); fn != nil {
+		return fn
+	}
+	return func(K, V) int64 { return 1 }
+}
+
+// evictionPolicy 从Options中取出淘汰策略工厂，未设置时使用LRU
+func evictionPolicy[K cmp.Ordered](v any) EvictionPolicyFactory[K] {
+	if factory := hook[EvictionPolicyFactory[K]](v); factory != nil {
+		return factory
+	}
+	return NewLRUPolicy[K]
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// lruPolicy 最近最少使用（LRU）淘汰策略，链表头部为最近访问的键
+//
+// gods 的 linkedhashmap 删除键需要线性查找，这里使用 container/list 保证各操作均为O(1)。
+type lruPolicy[K comparable] struct {
+	order *list.List
+	items map[K]*list.Element
+}
+
+// NewLRUPolicy 创建LRU淘汰策略
+func NewLRUPolicy[K comparable](capacity int) EvictionPolicy[K] {
+	return &lruPolicy[K]{
+		order: list.New(),
+		items: make(map[K]*list.Element, capacity),
+	}
+}
+
+func (l *lruPolicy[K]) Add(key K) {
+	l.Access(key)
+}
+
+func (l *lruPolicy[K]) Access(key K) {
+	if e, ok := l.items[key]; ok {
+		l.order.MoveToFront(e)
+		return
+	}
+	l.items[key] = l.order.PushFront(key)
+}
+
+func (l *lruPolicy[K]) Remove(key K) {
+	if e, ok := l.items[key]; ok {
+		l.order.Remove(e)
+		delete(l.items, key)
+	}
+}
+
+func (l *lruPolicy[K]) Evict() (key K, ok bool) {
+	return l.evict(l.order.Back())
+}
+
+func (l *lruPolicy[K]) evictExcept(key K) (K, bool) {
+	e := l.order.Back()
+	if e != nil && e.Value.(K) == key {
+		e = e.Prev()
+	}
+	return l.evict(e)
+}
+
+// evict 淘汰链表元素e对应的键
+func (l *lruPolicy[K]) evict(e *list.Element) (key K, ok bool) {
+	if e == nil {
+		return key, false
+	}
+	key = l.order.Remove(e).(K)
+	delete(l.items, key)
+	return key, true
+}
+
+func (l *lruPolicy[K]) Clear() {
+	l.order.Init()
+	clear(l.items)
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// lfuPolicy 最不经常使用（LFU）淘汰策略，访问次数相同时淘汰最久未访问的键，各操作均为O(1)
+type lfuPolicy[K comparable] struct {
+	items map[K]*lfuItem[K]
+	freqs *list.List // 按访问次数升序排列的 *lfuBucket，只保留非空的桶
+}
+
+// lfuBucket 访问次数相同的键
+type lfuBucket[K comparable] struct {
+	freq int
+	keys *list.List // 链表头部为最近访问
+}
+
+type lfuItem[K comparable] struct {
+	bucket *list.Element // 所在的访问次数桶
+	elem   *list.Element
+}
+
+// NewLFUPolicy 创建LFU淘汰策略
+func NewLFUPolicy[K comparable](capacity int) EvictionPolicy[K] {
+	return &lfuPolicy[K]{
+		items: make(map[K]*lfuItem[K], capacity),
+		freqs: list.New(),
+	}
+}
+
+func (l *lfuPolicy[K]) Add(key K) {
+	if _, ok := l.items[key]; ok {
+		l.Access(key)
+		return
+	}
+	front := l.freqs.Front()
+	if front == nil || front.Value.(*lfuBucket[K]).freq != 1 {
+		front = l.freqs.PushFront(&lfuBucket[K]{freq: 1, keys: list.New()})
+	}
+	l.items[key] = &lfuItem[K]{bucket: front, elem: front.Value.(*lfuBucket[K]).keys.PushFront(key)}
+}
+
+func (l *lfuPolicy[K]) Access(key K) {
+	item, ok := l.items[key]
+	if !ok {
+		l.Add(key)
+		return
+	}
+	current := item.bucket
+	freq := current.Value.(*lfuBucket[K]).freq + 1
+	next := current.Next()
+	if next == nil || next.Value.(*lfuBucket[K]).freq != freq {
+		next = l.freqs.InsertAfter(&lfuBucket[K]{freq: freq, keys: list.New()}, current)
+	}
+	l.unlink(item)
+	item.bucket = next
+	item.elem = next.Value.(*lfuBucket[K]).keys.PushFront(key)
+}
+
+func (l *lfuPolicy[K]) Remove(key K) {
+	if item, ok := l.items[key]; ok {
+		l.unlink(item)
+		delete(l.items, key)
+	}
+}
+
+func (l *lfuPolicy[K]) Evict() (key K, ok bool) {
+	front := l.freqs.Front()
+	if front == nil {
+		return key, false
+	}
+	key = front.Value.(*lfuBucket[K]).keys.Back().Value.(K)
+	l.Remove(key)
+	return key, true
+}
+
+func (l *lfuPolicy[K]) evictExcept(key K) (victim K, ok bool) {
+	// key 只会出现在一个桶中，最多查看两个桶
+	for bucket := l.freqs.Front(); bucket != nil; bucket = bucket.Next() {
+		e := bucket.Value.(*lfuBucket[K]).keys.Back()
+		if e.Value.(K) == key {
+			e = e.Prev()
+		}
+		if e != nil {
+			victim = e.Value.(K)
+			l.Remove(victim)
+			return victim, true
+		}
+	}
+	return victim, false
+}
+
+func (l *lfuPolicy[K]) Clear() {
+	clear(l.items)
+	l.freqs.Init()
+}
+
+// unlink 将键从所在的访问次数桶中移除，桶变空时一并移除
+func (l *lfuPolicy[K]) unlink(item *lfuItem[K]) {
+	bucket := item.bucket.Value.(*lfuBucket[K])
+	bucket.keys.Remove(item.elem)
+	if bucket.keys.Len() == 0 {
+		l.freqs.Remove(item.bucket)
+	}
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// arcPolicy 自适应替换缓存（ARC）淘汰策略
+//
+// t1 保存只访问过一次的键，t2 保存访问过多次的键；b1、b2 分别记录最近从 t1、t2 淘汰的键（幽灵列表）。
+// 新写入的键命中幽灵列表时调整 t1 的目标大小 p，从而在近期性与频率之间自适应。
+type arcPolicy[K comparable] struct {
+	capacity       int
+	p              int
+	t1, t2, b1, b2 *list.List
+	items          map[K]*arcItem
+}
+
+type arcItem struct {
+	owner *list.List
+	elem  *list.Element
+}
+
+// NewARCPolicy 创建ARC淘汰策略
+func NewARCPolicy[K comparable](capacity int) EvictionPolicy[K] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &arcPolicy[K]{
+		capacity: capacity,
+		t1:       list.New(),
+		t2:       list.New(),
+		b1:       list.New(),
+		b2:       list.New(),
+		items:    make(map[K]*arcItem, capacity*2),
+	}
+}
+
+func (a *arcPolicy[K]) Add(key K) {
+	item, ok := a.items[key]
+	if !ok {
+		a.push(a.t1, key)
+		a.trimGhosts()
+		return
+	}
+
+	switch item.owner {
+	case a.b1:
+		// 近期淘汰的键再次写入，说明 t1 过小
+		a.p = min(a.capacity, a.p+max(a.b2.Len()/a.b1.Len(), 1))
+	case a.b2:
+		// 高频淘汰的键再次写入，说明 t2 过小
+		a.p = max(0, a.p-max(a.b1.Len()/a.b2.Len(), 1))
+	}
+	a.unlink(key, item)
+	a.push(a.t2, key)
+	a.trimGhosts()
+}
+
+func (a *arcPolicy[K]) Access(key K) {
+	item, ok := a.items[key]
+	if !ok || (item.owner != a.t1 && item.owner != a.t2) {
+		a.Add(key)
+		return
+	}
+	a.unlink(key, item)
+	a.push(a.t2, key)
+}
+
+func (a *arcPolicy[K]) Remove(key K) {
+	if item, ok := a.items[key]; ok && (item.owner == a.t1 || item.owner == a.t2) {
+		a.unlink(key, item)
+	}
+}
+
+func (a *arcPolicy[K]) Evict() (key K, ok bool) {
+	from, ghost := a.t2, a.b2
+	if a.t1.Len() > 0 && (a.t1.Len() > a.p || a.t2.Len() == 0) {
+		from, ghost = a.t1, a.b1
+	}
+	return a.evict(from.Back(), ghost)
+}
+
+func (a *arcPolicy[K]) evictExcept(key K) (K, bool) {
+	// 按 Evict 的规则选择列表时不计入key，所选列表只剩key时改从另一个列表淘汰
+	t1, t2 := a.t1.Len(), a.t2.Len()
+	if item, ok := a.items[key]; ok && item.owner == a.t1 {
+		t1--
+	} else if ok && item.owner == a.t2 {
+		t2--
+	}
+	from, ghost, other, otherGhost := a.t2, a.b2, a.t1, a.b1
+	if t1 > 0 && (t1 > a.p || t2 == 0) {
+		from, ghost, other, otherGhost = a.t1, a.b1, a.t2, a.b2
+	}
+
+	e := from.Back()
+	if e != nil && e.Value.(K) == key {
+		e = e.Prev()
+	}
+	if e == nil {
+		from, ghost = other, otherGhost
+		if e = from.Back(); e != nil && e.Value.(K) == key {
+			e = e.Prev()
+		}
+	}
+	return a.evict(e, ghost)
+}
+
+// evict 淘汰链表元素e对应的键并移入幽灵列表ghost
+func (a *arcPolicy[K]) evict(e *list.Element, ghost *list.List) (key K, ok bool) {
+	if e == nil {
+		return key, false
+	}
+	key = e.Value.(K)
+	a.unlink(key, a.items[key])
+	a.push(ghost, key)
+	a.trimGhosts()
+	return key, true
+}
+
+func (a *arcPolicy[K]) Clear() {
+	a.p = 0
+	a.t1.Init()
+	a.t2.Init()
+	a.b1.Init()
+	a.b2.Init()
+	clear(a.items)
+}
+
+func (a *arcPolicy[K]) push(l *list.List, key K) {
+	a.items[key] = &arcItem{owner: l, elem: l.PushFront(key)}
+}
+
+func (a *arcPolicy[K]) unlink(key K, item *arcItem) {
+	item.owner.Remove(item.elem)
+	delete(a.items, key)
+}
+
+// trimGhosts 限制幽灵列表的长度
+func (a *arcPolicy[K]) trimGhosts() {
+	for a.b1.Len() > 0 && a.t1.Len()+a.b1.Len() > a.capacity {
+		key := a.b1.Back().Value.(K)
+		a.unlink(key, a.items[key])
+	}
+	for a.b2.Len() > 0 && a.t1.Len()+a.t2.Len()+a.b1.Len()+a.b2.Len() > 2*a.capacity {
+		key := a.b2.Back().Value.(K)
+		a.unlink(key, a.items[key])
+	}
+}
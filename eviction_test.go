@@ -0,0 +1,385 @@
+package cmap
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestCapacityLRU 测试容量限制与LRU淘汰
+func TestCapacityLRU(t *testing.T) {
+	m := NewStringHashMap[int](WithShardCount(1), WithCapacity(3))
+	m.Put("a", 1)
+	m.Put("b", 2)
+	m.Put("c", 3)
+
+	// 访问a，使b成为最近最少使用的键
+	m.Get("a")
+	m.Put("d", 4)
+
+	if m.Size() != 3 {
+		t.Errorf("Size should be capped at 3, got %d", m.Size())
+	}
+	if _, ok := m.Get("b"); ok {
+		t.Error("Least recently used key b should be evicted")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := m.Get(key); !ok {
+			t.Errorf("Key %s should still exist", key)
+		}
+	}
+
+	// 更新已存在的键同样刷新访问顺序
+	m.Put("c", 30)
+	m.Put("e", 5)
+	if _, ok := m.Get("a"); ok {
+		t.Error("Key a should be evicted after c was updated")
+	}
+}
+
+// TestCapacityAcrossShards 测试容量按分片均分
+func TestCapacityAcrossShards(t *testing.T) {
+	m := NewIntHashMap[int](WithShardCount(8), WithCapacity(80))
+	for i := 0; i < 1000; i++ {
+		m.Put(i, i)
+	}
+
+	if m.Size() > 80 {
+		t.Errorf("Size should not exceed capacity 80, got %d", m.Size())
+	}
+	for i := range m.shards {
+		m.shards[i].mu.RLock()
+		size := m.shards[i].m.Size()
+		m.shards[i].mu.RUnlock()
+		if size > 10 {
+			t.Errorf("Shard %d holds %d keys, want at most 10", i, size)
+		}
+	}
+}
+
+// TestCapacityRemoveAndClear 测试删除与清空后的容量
+func TestCapacityRemoveAndClear(t *testing.T) {
+	m := NewStringHashMap[int](WithShardCount(1), WithCapacity(2))
+	m.Put("a", 1)
+	m.Put("b", 2)
+	m.Remove("a")
+	m.Put("c", 3)
+
+	if _, ok := m.Get("b"); !ok {
+		t.Error("Removing a key should free capacity")
+	}
+
+	m.Clear()
+	m.Put("x", 1)
+	m.Put("y", 2)
+	if m.Size() != 2 {
+		t.Errorf("Size after Clear got %d, want 2", m.Size())
+	}
+}
+
+// TestCapacityConcurrent 测试容量限制下的并发访问
+func TestCapacityConcurrent(t *testing.T) {
+	m := NewStringHashMap[int](WithShardCount(4), WithCapacity(100))
+	const goroutines = 20
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				key := fmt.Sprintf("key-%d-%d", id, j)
+				m.Put(key, j)
+				m.Get(key)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if m.Size() > 100 {
+		t.Errorf("Size should not exceed capacity 100, got %d", m.Size())
+	}
+}
+
+// TestLRUPolicy 测试LRU淘汰策略
+func TestLRUPolicy(t *testing.T) {
+	p := NewLRUPolicy[string](3)
+	if _, ok := p.Evict(); ok {
+		t.Error("Empty policy should have no victim")
+	}
+
+	p.Add("a")
+	p.Add("b")
+	p.Add("c")
+	p.Access("a")
+	if victim, _ := p.Evict(); victim != "b" {
+		t.Errorf("Evict got %s, want b", victim)
+	}
+
+	p.Remove("c")
+	if victim, _ := p.Evict(); victim != "a" {
+		t.Errorf("Evict after remove got %s, want a", victim)
+	}
+
+	p.Add("x")
+	p.Clear()
+	if _, ok := p.Evict(); ok {
+		t.Error("Cleared policy should have no victim")
+	}
+}
+
+// TestLFUPolicy 测试LFU淘汰策略
+func TestLFUPolicy(t *testing.T) {
+	p := NewLFUPolicy[string](3)
+	p.Add("a")
+	p.Add("b")
+	p.Add("c")
+	p.Access("a")
+	p.Access("a")
+	p.Access("c")
+
+	// b访问次数最少
+	if victim, _ := p.Evict(); victim != "b" {
+		t.Errorf("Evict got %s, want b", victim)
+	}
+	// c访问2次，a访问3次
+	if victim, _ := p.Evict(); victim != "c" {
+		t.Errorf("Evict got %s, want c", victim)
+	}
+
+	// 访问次数相同时淘汰最久未访问的键
+	p.Add("d")
+	p.Add("e")
+	p.Remove("a")
+	if victim, _ := p.Evict(); victim != "d" {
+		t.Errorf("Evict got %s, want d", victim)
+	}
+
+	p.Clear()
+	if _, ok := p.Evict(); ok {
+		t.Error("Cleared policy should have no victim")
+	}
+}
+
+// TestARCPolicy 测试ARC淘汰策略
+func TestARCPolicy(t *testing.T) {
+	p := NewARCPolicy[string](2)
+	p.Add("a")
+	p.Add("b")
+	p.Access("a") // a进入t2
+
+	// t1中的b优先被淘汰
+	if victim, _ := p.Evict(); victim != "b" {
+		t.Errorf("Evict got %s, want b", victim)
+	}
+
+	// b命中幽灵列表后直接进入t2，并增大t1的目标大小
+	p.Add("b")
+	arc := p.(*arcPolicy[string])
+	if arc.p == 0 {
+		t.Error("Ghost hit in b1 should increase target size p")
+	}
+	if arc.items["b"].owner != arc.t2 {
+		t.Error("Ghost hit should move key to t2")
+	}
+
+	p.Remove("a")
+	if victim, _ := p.Evict(); victim != "b" {
+		t.Errorf("Evict got %s, want b", victim)
+	}
+	if _, ok := p.Evict(); ok {
+		t.Error("Policy without resident keys should have no victim")
+	}
+}
+
+// TestEvictionPolicyOption 测试通过选项选择淘汰策略
+func TestEvictionPolicyOption(t *testing.T) {
+	var evicted []string
+	m := NewStringHashMap[int](
+		WithShardCount(1),
+		WithCapacity(3),
+		WithEvictionPolicy(NewLFUPolicy[string]),
+		WithOnEvict(func(key string, value int) {
+			evicted = append(evicted, key)
+		}),
+	)
+
+	m.Put("a", 1)
+	m.Put("b", 2)
+	m.Put("c", 3)
+	for i := 0; i < 5; i++ {
+		m.Get("a")
+		m.Get("c")
+	}
+	m.Get("b")
+	// b访问次数最少，新写入的d不会被立即淘汰
+	m.Put("d", 4)
+	m.Put("e", 5)
+
+	if len(evicted) != 2 || evicted[0] != "b" || evicted[1] != "d" {
+		t.Errorf("LFU evicted %v, want [b d]", evicted)
+	}
+	if _, ok := m.Get("e"); !ok {
+		t.Error("Newly inserted key should not be evicted")
+	}
+}
+
+// TestEvictionPolicyARCOption 测试ARC策略下的映射行为
+func TestEvictionPolicyARCOption(t *testing.T) {
+	evictions := 0
+	m := NewIntHashMap[int](
+		WithShardCount(1),
+		WithCapacity(10),
+		WithEvictionPolicy(NewARCPolicy[int]),
+		WithOnEvict(func(key int, value int) { evictions++ }),
+	)
+
+	for i := 0; i < 100; i++ {
+		m.Put(i%20, i)
+		m.Get(i % 5)
+	}
+
+	if m.Size() != 10 {
+		t.Errorf("Size got %d, want 10", m.Size())
+	}
+	if evictions == 0 {
+		t.Error("OnEvict should be called")
+	}
+	// 频繁访问的键应当被保留
+	for i := 0; i < 5; i++ {
+		if _, ok := m.Get(i); !ok {
+			t.Errorf("Hot key %d should not be evicted", i)
+		}
+	}
+}
+
+// TestCapacityTotalLimit 测试默认分片数量下键数量不超过容量
+func TestCapacityTotalLimit(t *testing.T) {
+	for _, capacity := range []int{1, 7, 100, 1000} {
+		m := NewIntHashMap[int](WithShardCount(256), WithCapacity(capacity))
+		for i := 0; i < capacity*20; i++ {
+			m.Put(i, i)
+		}
+		if m.Size() > capacity {
+			t.Errorf("Capacity %d: size %d exceeds capacity", capacity, m.Size())
+		}
+		total := 0
+		for i := range m.shards {
+			if m.shards[i].capacity < 1 {
+				t.Errorf("Capacity %d: shard %d has capacity %d", capacity, i, m.shards[i].capacity)
+			}
+			total += m.shards[i].capacity
+		}
+		if total != capacity {
+			t.Errorf("Capacity %d: shard capacities sum to %d", capacity, total)
+		}
+	}
+}
+
+// TestCapacityExpiredFirst 测试超出容量时先删除已过期的键，而不是淘汰未过期的键
+func TestCapacityExpiredFirst(t *testing.T) {
+	clock := newFakeClock()
+	var expired, evicted []string
+	m := NewStringHashMap[int](
+		WithClock(clock.Now),
+		WithShardCount(1),
+		WithCapacity(2),
+		WithOnExpire(func(key string, value int) { expired = append(expired, key) }),
+		WithOnEvict(func(key string, value int) { evicted = append(evicted, key) }),
+	)
+	m.PutWithTTL("a", 1, time.Second)
+	m.Put("b", 2)
+	m.Get("a")
+
+	clock.Advance(2 * time.Second)
+	m.Put("c", 3)
+	if keys := m.Keys(); len(keys) != 2 {
+		t.Errorf("Keys got %v, want [b c]", keys)
+	}
+	if len(expired) != 1 || expired[0] != "a" {
+		t.Errorf("Expired %v, want [a]", expired)
+	}
+	if len(evicted) != 0 {
+		t.Errorf("Evicted %v, want none", evicted)
+	}
+}
+
+// TestLFUPolicyRemoveGaps 测试删除键后留下的访问次数空隙
+func TestLFUPolicyRemoveGaps(t *testing.T) {
+	p := NewLFUPolicy[string](3)
+	p.Add("a")
+	p.Add("b")
+	for i := 0; i < 1000; i++ {
+		p.Access("b")
+	}
+	p.Remove("a")
+
+	// 只保留非空的访问次数桶，Evict 无需逐个跳过空桶
+	if n := p.(*lfuPolicy[string]).freqs.Len(); n != 1 {
+		t.Errorf("Policy should keep 1 frequency bucket, got %d", n)
+	}
+	if victim, _ := p.Evict(); victim != "b" {
+		t.Errorf("Evict got %s, want b", victim)
+	}
+}
+
+// TestEvictExcept 测试淘汰时跳过正在写入的键且保留其状态
+func TestEvictExcept(t *testing.T) {
+	lfu := NewLFUPolicy[string](3).(*lfuPolicy[string])
+	lfu.Add("a")
+	lfu.Add("b")
+	lfu.Access("b")
+	if victim, _ := lfu.evictExcept("a"); victim != "b" {
+		t.Errorf("LFU evictExcept got %s, want b", victim)
+	}
+	if freq := lfu.items["a"].bucket.Value.(*lfuBucket[string]).freq; freq != 1 {
+		t.Errorf("Skipped key should keep frequency 1, got %d", freq)
+	}
+	if _, ok := lfu.evictExcept("a"); ok {
+		t.Error("Only the skipped key remains, should have no victim")
+	}
+
+	lru := NewLRUPolicy[string](3).(*lruPolicy[string])
+	lru.Add("a")
+	lru.Add("b")
+	if victim, _ := lru.evictExcept("a"); victim != "b" {
+		t.Errorf("LRU evictExcept got %s, want b", victim)
+	}
+
+	arc := NewARCPolicy[string](2).(*arcPolicy[string])
+	arc.Add("a")
+	arc.Add("b")
+	arc.Access("b") // a在t1，b在t2
+	if victim, _ := arc.evictExcept("a"); victim != "b" {
+		t.Errorf("ARC evictExcept got %s, want b", victim)
+	}
+	if arc.items["a"].owner != arc.t1 || arc.p != 0 {
+		t.Error("Skipped key should stay in t1 without changing p")
+	}
+}
+
+// TestMaxCostKeepsWrittenKeyState 测试成本淘汰跳过正在写入的键时不重置其访问次数
+func TestMaxCostKeepsWrittenKeyState(t *testing.T) {
+	m := NewStringHashMap[int](
+		WithShardCount(1),
+		WithMaxCost(10),
+		WithEvictionPolicy(NewLFUPolicy[string]),
+		WithWeigher(func(key string, value int) int64 { return int64(value) }),
+	)
+	m.Put("a", 1)
+	m.Put("b", 1)
+	m.Get("a")
+	m.Get("b")
+	m.Get("b")
+
+	// a访问次数最少，但正在写入，应淘汰b
+	m.Put("a", 10)
+	if _, ok := m.Get("b"); ok {
+		t.Error("Key b should be evicted")
+	}
+	lfu := m.shards[0].policy.(*lfuPolicy[string])
+	if freq := lfu.items["a"].bucket.Value.(*lfuBucket[string]).freq; freq != 3 {
+		t.Errorf("Written key should keep its frequency, got %d, want 3", freq)
+	}
+}
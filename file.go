@@ -6,64 +6,40 @@ import (
 	"path/filepath"
 )
 
-// SaveToFile 保存到文件
+// SaveToFile 保存到文件，保存的是调用时刻所有分片的一致快照（见 Snapshot）
 func (m *Map[K, V]) SaveToFile(filename string) (err error) {
 	if filename == "" {
 		return fmt.Errorf("filename cannot be empty")
 	}
 
-	// 检查文件路径是否有效
-	if !filepath.IsAbs(filename) {
-		// 对于相对路径，确保目录存在
-		dir := filepath.Dir(filename)
-		if dir != "." && dir != "" {
-			if err := os.MkdirAll(dir, 0755); err != nil {
-				return fmt.Errorf("failed to create directory %s: %w", dir, err)
-			}
-		}
-	}
-
 	if m.opts.Serializer == nil || m.opts.Serializer.MarshalFunc == nil {
 		return fmt.Errorf("no serializer configured for marshaling")
 	}
 
-	size := m.Size()
-	if size > 0 {
-		m.mu.RLock()
-		isDirty := m.dirty
-		m.mu.RUnlock()
-		if !isDirty {
-			// 如果未修改，则不保存，避免频繁的文件写入
-			return nil
-		}
+	// 如果未修改，则不保存，避免频繁的文件写入；先检查版本号，未修改时无需创建快照
+	if m.Size() > 0 && !m.IsDirty() {
+		return nil
 	}
 
-	data, err := m.MarshalWith(m.opts.Serializer)
+	s := m.Snapshot()
+	defer s.Release()
+
+	data, err := s.MarshalWith(m.opts.Serializer)
 	if err != nil {
 		return fmt.Errorf("failed to marshal data: %w", err)
 	}
 
-	// 先写入临时文件
-	tempFile := filename + ".tmp"
-	if err = os.WriteFile(tempFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write temporary file %s: %w", tempFile, err)
+	if err = writeFile(filename, data); err != nil {
+		return err
 	}
 
-	// 原子性重命名
-	if err = os.Rename(tempFile, filename); err != nil {
-		_ = os.Remove(tempFile)
-		return fmt.Errorf("failed to rename temporary file to %s: %w", filename, err)
-	}
-
-	// 标记为未修改
-	m.mu.Lock()
-	m.dirty = false
-	m.mu.Unlock()
+	// 标记快照时刻的数据为已保存，快照之后的修改仍视为未保存
+	m.markSaved(s.versions)
 
 	return nil
 }
 
-// LoadFromFile 从文件加载
+// LoadFromFile 从文件加载，加载的键值对不会写回 Store（见 UnmarshalWith）
 func (m *Map[K, V]) LoadFromFile(filename string) error {
 	if filename == "" {
 		return fmt.Errorf("filename cannot be empty")
@@ -87,9 +63,7 @@ func (m *Map[K, V]) LoadFromFile(filename string) error {
 	if len(data) == 0 {
 		// 空文件是合法的，清空当前映射
 		m.Clear()
-		m.mu.Lock()
-		m.dirty = false
-		m.mu.Unlock()
+		m.markSaved(m.versions())
 		return nil
 	}
 
@@ -99,9 +73,34 @@ func (m *Map[K, V]) LoadFromFile(filename string) error {
 	}
 
 	// 加载完成后标记为未修改，因为数据与文件同步
-	m.mu.Lock()
-	m.dirty = false
-	m.mu.Unlock()
+	m.markSaved(m.versions())
 
 	return nil
 }
+
+// writeFile 先写入临时文件再原子性重命名，相对路径的目录不存在时自动创建
+func writeFile(filename string, data []byte) error {
+	// 检查文件路径是否有效
+	if !filepath.IsAbs(filename) {
+		// 对于相对路径，确保目录存在
+		dir := filepath.Dir(filename)
+		if dir != "." && dir != "" {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", dir, err)
+			}
+		}
+	}
+
+	// 先写入临时文件
+	tempFile := filename + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write temporary file %s: %w", tempFile, err)
+	}
+
+	// 原子性重命名
+	if err := os.Rename(tempFile, filename); err != nil {
+		_ = os.Remove(tempFile)
+		return fmt.Errorf("failed to rename temporary file to %s: %w", filename, err)
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"sync"
 	"testing"
+	"time"
 )
 
 // TestSaveToFile 测试保存到文件
@@ -31,6 +32,31 @@ func TestSaveToFile(t *testing.T) {
 	}
 }
 
+// TestSaveToFileClean 测试未修改时不创建快照
+func TestSaveToFileClean(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "test_clean.json")
+	m := NewStringHashMap[int](WithSerializer(JsonSerializer()))
+	m.Put("key1", 100)
+	if err := m.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+
+	// 创建快照需要所有分片的锁，未修改时保存不应等待被占用的分片
+	sh := m.getShard("key1")
+	sh.mu.Lock()
+	done := make(chan error)
+	go func() { done <- m.SaveToFile(tmpFile) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("SaveToFile failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("SaveToFile of a clean map should not take a snapshot")
+	}
+	sh.mu.Unlock()
+}
+
 // TestLoadFromFile 测试从文件加载
 func TestLoadFromFile(t *testing.T) {
 	// 创建临时文件
@@ -1112,3 +1138,90 @@ func TestFileErrorHandling(t *testing.T) {
 		})
 	}
 }
+
+// TestSaveToFileDirtyShards 测试按分片记录修改标记
+func TestSaveToFileDirtyShards(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "dirty.json")
+	m := NewStringHashMap[int](WithShardCount(8), WithSerializer(JsonSerializer()))
+	if m.IsDirty() {
+		t.Error("New map should not be dirty")
+	}
+
+	m.Put("a", 1)
+	if shards := m.changed(m.versions()); len(shards) != 1 || uint32(shards[0]) != m.hasher.Hash("a")&m.mask {
+		t.Errorf("Only the shard of a should be changed, got %v", shards)
+	}
+	if err := m.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+	if m.IsDirty() {
+		t.Error("Saved map should not be dirty")
+	}
+
+	// 未修改时不重写文件
+	_ = os.Remove(tmpFile)
+	if err := m.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+	if _, err := os.Stat(tmpFile); !os.IsNotExist(err) {
+		t.Error("SaveToFile should skip clean map")
+	}
+
+	// 删除不存在的键不算修改
+	m.Remove("missing")
+	if m.IsDirty() {
+		t.Error("Removing missing key should not mark map dirty")
+	}
+	m.Remove("a")
+	if !m.IsDirty() {
+		t.Error("Remove should mark map dirty")
+	}
+}
+
+// TestSaveToFileConcurrentWrites 测试保存期间的写入仍视为未保存
+func TestSaveToFileConcurrentWrites(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "concurrent.json")
+	m := NewStringHashMap[int](WithSerializer(JsonSerializer()))
+	m.Put("a", 1)
+
+	s := m.Snapshot()
+	m.Put("b", 2)
+	m.markSaved(s.versions)
+	if !m.IsDirty() {
+		t.Error("Write after snapshot should keep map dirty")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.Put(fmt.Sprintf("key%d_%d", i, j), j)
+			}
+		}(i)
+	}
+	for i := 0; i < 5; i++ {
+		if err := m.SaveToFile(tmpFile); err != nil {
+			t.Fatalf("SaveToFile failed: %v", err)
+		}
+	}
+	wg.Wait()
+
+	if err := m.SaveToFile(tmpFile); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+	if m.IsDirty() {
+		t.Error("Map should be clean after final save")
+	}
+	loaded := NewStringHashMap[int](WithSerializer(JsonSerializer()))
+	if err := loaded.LoadFromFile(tmpFile); err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if loaded.Size() != m.Size() {
+		t.Errorf("Loaded size got %d, want %d", loaded.Size(), m.Size())
+	}
+	if loaded.IsDirty() {
+		t.Error("Loaded map should not be dirty")
+	}
+}
@@ -180,8 +180,8 @@ func TestHashDistribution(t *testing.T) {
 	for _, key := range keys {
 		shard := m.getShard(key)
 		shardIndex := uint32(0)
-		for i, s := range m.shards {
-			if s == *shard {
+		for i := range m.shards {
+			if &m.shards[i] == shard {
 				shardIndex = uint32(i)
 				break
 			}
@@ -0,0 +1,22 @@
+package cmap
+
+// TryPut 插入键值对，WithBeforePut 拒绝写入时返回其错误且映射保持不变
+func (m *Map[K, V]) TryPut(key K, value V) error {
+	if err := m.check(key, value); err != nil {
+		return err
+	}
+
+	sh := m.getShard(key)
+	sh.mu.Lock()
+	sh.put(key, value)
+	m.unlock(sh)
+	return nil
+}
+
+// check 调用 WithBeforePut 设置的校验函数
+func (m *Map[K, V]) check(key K, value V) error {
+	if m.beforePut == nil {
+		return nil
+	}
+	return m.beforePut(key, value)
+}
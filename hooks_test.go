@@ -0,0 +1,141 @@
+package cmap
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// auditLog 记录回调调用
+type auditLog struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (a *auditLog) add(entry string) {
+	a.mu.Lock()
+	a.entries = append(a.entries, entry)
+	a.mu.Unlock()
+}
+
+func (a *auditLog) take() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entries := a.entries
+	a.entries = nil
+	sort.Strings(entries)
+	return entries
+}
+
+func newAuditedMap(log *auditLog, options ...Option) *Map[string, int] {
+	options = append(options,
+		WithOnPut(func(key string, oldValue, newValue int, replaced bool) {
+			if replaced {
+				log.add("update " + key)
+			} else {
+				log.add("insert " + key)
+			}
+		}),
+		WithOnRemove(func(key string, value int) {
+			log.add("remove " + key)
+		}),
+	)
+	return NewStringHashMap[int](options...)
+}
+
+func equalEntries(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestHooks 测试写入与删除回调
+func TestHooks(t *testing.T) {
+	log := &auditLog{}
+	m := newAuditedMap(log)
+
+	tests := []struct {
+		name string
+		op   func()
+		want []string
+	}{
+		{"Put", func() { m.Put("a", 1) }, []string{"insert a"}},
+		{"PutUpdate", func() { m.Put("a", 2) }, []string{"update a"}},
+		{"PutAll", func() { m.PutAll(map[string]int{"b": 1, "c": 1}) }, []string{"insert b", "insert c"}},
+		{"TryPut", func() { _ = m.TryPut("d", 1) }, []string{"insert d"}},
+		{"Compute", func() { m.Compute("d", func(int, bool) (int, bool) { return 0, false }) }, []string{"remove d"}},
+		{"Remove", func() { m.Remove("a") }, []string{"remove a"}},
+		{"RemoveMissing", func() { m.Remove("a") }, nil},
+		{"RemoveMultiple", func() { m.RemoveMultiple([]string{"b", "x"}) }, []string{"remove b"}},
+		{"Clear", func() { m.Put("e", 1); log.take(); m.Clear() }, []string{"remove c", "remove e"}},
+		{"UnmarshalWith", func() {
+			m.Put("f", 1)
+			src := NewStringHashMap[int]()
+			src.Put("g", 1)
+			data, _ := src.MarshalJSON()
+			log.take()
+			_ = m.UnmarshalJSON(data)
+		}, []string{"insert g", "remove f"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.op()
+			if got := log.take(); !equalEntries(got, tt.want) {
+				t.Errorf("Hooks got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBeforePut 测试写入前校验
+func TestBeforePut(t *testing.T) {
+	errNegative := errors.New("negative value")
+	m := NewStringHashMap[int](WithBeforePut(func(key string, value int) error {
+		if value < 0 {
+			return errNegative
+		}
+		return nil
+	}))
+
+	if err := m.TryPut("a", 1); err != nil {
+		t.Errorf("TryPut valid value failed: %v", err)
+	}
+	if err := m.TryPut("a", -1); !errors.Is(err, errNegative) {
+		t.Errorf("TryPut error got %v, want %v", err, errNegative)
+	}
+
+	m.Put("a", -2)
+	m.PutAll(map[string]int{"a": -3, "b": -3})
+	m.Swap("a", -4)
+	m.CompareAndSwap("a", 1, -5)
+	m.Compute("a", func(int, bool) (int, bool) { return -6, true })
+	m.ComputeIfPresent("a", func(string, int) (int, bool) { return -7, true })
+	m.ComputeIfAbsent("c", func(string) int { return -8 })
+	m.PutIfAbsent("d", -9)
+
+	if value, _ := m.Get("a"); value != 1 {
+		t.Errorf("Rejected writes should keep value 1, got %v", value)
+	}
+	if m.Size() != 1 {
+		t.Errorf("Rejected inserts should not add keys, size %d, want 1", m.Size())
+	}
+
+	// 反序列化时任一键值对被拒绝则不修改映射
+	src := NewStringHashMap[int]()
+	src.PutAll(map[string]int{"x": 1, "y": -1})
+	data, _ := src.MarshalJSON()
+	if err := m.UnmarshalJSON(data); !errors.Is(err, errNegative) {
+		t.Errorf("UnmarshalJSON error got %v, want %v", err, errNegative)
+	}
+	if value, _ := m.Get("a"); value != 1 || m.Size() != 1 {
+		t.Error("Rejected UnmarshalJSON should not modify map")
+	}
+}
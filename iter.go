@@ -0,0 +1,33 @@
+//go:build go1.23
+
+package cmap
+
+import "iter"
+
+// All 返回遍历所有键值对的迭代器，可用于 for k, v := range m.All()
+//
+// 迭代基于分片快照：每个分片在读锁内复制后再逐个产出，循环体内可以安全地读写当前映射。
+// 同一分片内的数据是一致的，但不同分片的复制时刻不同，迭代期间的修改可能不可见。
+func (m *Map[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		m.RangeSafe(yield)
+	}
+}
+
+// KeysSeq 返回遍历所有键的迭代器，一致性保证同 All
+func (m *Map[K, V]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		m.RangeSafe(func(key K, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// ValuesSeq 返回遍历所有值的迭代器，一致性保证同 All
+func (m *Map[K, V]) ValuesSeq() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		m.RangeSafe(func(_ K, value V) bool {
+			return yield(value)
+		})
+	}
+}
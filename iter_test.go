@@ -0,0 +1,87 @@
+//go:build go1.23
+
+package cmap
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+// TestAll 测试All迭代器
+func TestAll(t *testing.T) {
+	m := NewStringHashMap[int]()
+	data := map[string]int{"a": 1, "b": 2, "c": 3}
+	m.PutAll(data)
+
+	got := make(map[string]int)
+	for k, v := range m.All() {
+		got[k] = v
+	}
+	if !maps.Equal(got, data) {
+		t.Errorf("All got %v, want %v", got, data)
+	}
+
+	if collected := maps.Collect(m.All()); !maps.Equal(collected, data) {
+		t.Errorf("maps.Collect got %v, want %v", collected, data)
+	}
+}
+
+// TestKeysValuesSeq 测试KeysSeq和ValuesSeq迭代器
+func TestKeysValuesSeq(t *testing.T) {
+	m := NewStringHashMap[int]()
+	m.PutAll(map[string]int{"c": 3, "a": 1, "b": 2})
+
+	keys := slices.Sorted(m.KeysSeq())
+	if !slices.Equal(keys, []string{"a", "b", "c"}) {
+		t.Errorf("KeysSeq got %v, want [a b c]", keys)
+	}
+
+	values := slices.Sorted(m.ValuesSeq())
+	if !slices.Equal(values, []int{1, 2, 3}) {
+		t.Errorf("ValuesSeq got %v, want [1 2 3]", values)
+	}
+}
+
+// TestIterInterop 测试与标准库迭代器函数的互操作
+func TestIterInterop(t *testing.T) {
+	src := NewStringHashMap[int]()
+	src.PutAll(map[string]int{"a": 1, "b": 2})
+
+	dst := map[string]int{"z": 26}
+	maps.Insert(dst, src.All())
+	if len(dst) != 3 || dst["a"] != 1 || dst["b"] != 2 {
+		t.Errorf("maps.Insert got %v", dst)
+	}
+
+	m := NewStringHashMap[int]()
+	for k, v := range maps.All(dst) {
+		m.Put(k, v)
+	}
+	if m.Size() != 3 {
+		t.Errorf("Map should accept values from maps.All, got size %d", m.Size())
+	}
+}
+
+// TestAllBreakAndMutate 测试迭代中断及循环体内修改映射
+func TestAllBreakAndMutate(t *testing.T) {
+	m := NewStringHashMap[int]()
+	for i := 0; i < 50; i++ {
+		m.Put(string(rune('A'+i)), i)
+	}
+
+	count := 0
+	for k := range m.KeysSeq() {
+		m.Remove(k)
+		count++
+		if count == 10 {
+			break
+		}
+	}
+	if count != 10 {
+		t.Errorf("Iteration should stop after break, visited %d", count)
+	}
+	if m.Size() != 40 {
+		t.Errorf("Loop body should be able to remove keys, size %d, want 40", m.Size())
+	}
+}
@@ -0,0 +1,112 @@
+package cmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// loadCall 一次正在进行的加载，同一个键的并发请求共享其结果
+type loadCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// negative 缓存的加载错误
+type negative struct {
+	err      error
+	deadline int64 // 过期时间（UnixNano）
+}
+
+// GetOrLoad 获取值，不存在时调用loader加载并写入映射
+//
+// 同一个键同时只会有一次加载，并发的请求等待并共享该次加载的结果。加载成功的值按默认TTL写入映射；
+// 加载失败的错误默认不缓存，设置 WithNegativeCache 后在指定时间内直接返回该错误。
+// 等待其他请求的加载结果时，ctx 结束会提前返回 ctx.Err()。loader 使用发起加载的请求的 ctx，
+// 该 ctx 结束导致加载返回 context.Canceled 或 context.DeadlineExceeded 时，仍在等待的请求会重新加载，该错误也不会被缓存。
+// loader 为 nil 时从 WithStore 设置的 Store 读取，Store 中不存在该键时返回 ErrNotFound。加载的值不会写回 Store。
+func (m *Map[K, V]) GetOrLoad(ctx context.Context, key K, loader func(ctx context.Context, key K) (V, error)) (V, error) {
+	if value, ok := m.Get(key); ok {
+		return value, nil
+	}
+	if loader == nil {
+		if m.writer == nil {
+			var zero V
+			return zero, fmt.Errorf("cmap: no loader or store configured")
+		}
+		loader = m.loadFromStore
+	}
+
+	for {
+		m.loadMu.Lock()
+		if n, ok := m.negatives[key]; ok {
+			if n.deadline > m.opts.now() {
+				m.loadMu.Unlock()
+				var zero V
+				return zero, n.err
+			}
+			delete(m.negatives, key)
+		}
+		if call, ok := m.loads[key]; ok {
+			m.loadMu.Unlock()
+			select {
+			case <-call.done:
+				if canceled(call.err) && ctx.Err() == nil {
+					// 加载者的 ctx 已结束，当前请求仍然有效，重新加载
+					continue
+				}
+				return call.value, call.err
+			case <-ctx.Done():
+				var zero V
+				return zero, ctx.Err()
+			}
+		}
+		call := &loadCall[V]{done: make(chan struct{})}
+		m.loads[key] = call
+		m.loadMu.Unlock()
+
+		m.load(ctx, key, call, loader)
+		return call.value, call.err
+	}
+}
+
+// canceled 检查错误是否由 ctx 取消或超时引起
+func canceled(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// load 执行加载并通知等待者
+func (m *Map[K, V]) load(ctx context.Context, key K, call *loadCall[V], loader func(ctx context.Context, key K) (V, error)) {
+	normal := false
+	defer func() {
+		if !normal {
+			// loader panic 时先唤醒等待者再继续传播
+			call.err = fmt.Errorf("cmap: loader panicked for key %v", key)
+		}
+		m.loadMu.Lock()
+		delete(m.loads, key)
+		if normal && call.err != nil && !canceled(call.err) && m.opts.NegativeTTL > 0 {
+			m.negatives[key] = negative{err: call.err, deadline: m.opts.now() + int64(m.opts.NegativeTTL)}
+		}
+		m.loadMu.Unlock()
+		close(call.done)
+	}()
+
+	// 成为加载者之前其他请求可能已经写入了值
+	if value, ok := m.Get(key); ok {
+		call.value = value
+		normal = true
+		return
+	}
+
+	call.value, call.err = loader(ctx, key)
+	if call.err == nil {
+		if call.err = m.fill(key, call.value); call.err != nil {
+			// 被 WithBeforePut 拒绝的值不返回给调用方
+			var zero V
+			call.value = zero
+		}
+	}
+	normal = true
+}
@@ -0,0 +1,226 @@
+package cmap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestGetOrLoad 测试读穿加载
+func TestGetOrLoad(t *testing.T) {
+	m := NewStringHashMap[int]()
+	loads := 0
+	loader := func(ctx context.Context, key string) (int, error) {
+		loads++
+		return len(key), nil
+	}
+
+	value, err := m.GetOrLoad(context.Background(), "hello", loader)
+	if err != nil || value != 5 {
+		t.Errorf("GetOrLoad got (%v, %v), want (5, nil)", value, err)
+	}
+
+	value, err = m.GetOrLoad(context.Background(), "hello", loader)
+	if err != nil || value != 5 {
+		t.Errorf("Second GetOrLoad got (%v, %v), want (5, nil)", value, err)
+	}
+	if loads != 1 {
+		t.Errorf("Loader should be called once, called %d times", loads)
+	}
+	if cached, ok := m.Get("hello"); !ok || cached != 5 {
+		t.Error("Loaded value should be stored in map")
+	}
+}
+
+// TestGetOrLoadSingleflight 测试并发加载去重
+func TestGetOrLoadSingleflight(t *testing.T) {
+	m := NewStringHashMap[string]()
+	var loads int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (string, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return "value-" + key, nil
+	}
+
+	const goroutines = 200
+	var wg sync.WaitGroup
+	var ready sync.WaitGroup
+	results := make([]string, goroutines)
+	wg.Add(goroutines)
+	ready.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			ready.Done()
+			results[id], _ = m.GetOrLoad(context.Background(), "db", loader)
+		}(i)
+	}
+	ready.Wait()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if loads != 1 {
+		t.Errorf("Loader should be called exactly once, called %d times", loads)
+	}
+	for i, result := range results {
+		if result != "value-db" {
+			t.Fatalf("Result %d got %q, want value-db", i, result)
+		}
+	}
+}
+
+// TestGetOrLoadError 测试加载失败不缓存
+func TestGetOrLoadError(t *testing.T) {
+	m := NewStringHashMap[int]()
+	errNotFound := errors.New("not found")
+	loads := 0
+	loader := func(ctx context.Context, key string) (int, error) {
+		loads++
+		return 0, errNotFound
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := m.GetOrLoad(context.Background(), "missing", loader); !errors.Is(err, errNotFound) {
+			t.Errorf("GetOrLoad error got %v, want %v", err, errNotFound)
+		}
+	}
+	if loads != 3 {
+		t.Errorf("Errors should not be cached by default, loader called %d times", loads)
+	}
+	if _, ok := m.Get("missing"); ok {
+		t.Error("Failed load should not store value")
+	}
+}
+
+// TestGetOrLoadNegativeCache 测试加载失败缓存
+func TestGetOrLoadNegativeCache(t *testing.T) {
+	clock := newFakeClock()
+	m := NewStringHashMap[int](WithClock(clock.Now), WithNegativeCache(time.Minute))
+	errNotFound := errors.New("not found")
+	loads := 0
+	loader := func(ctx context.Context, key string) (int, error) {
+		loads++
+		if loads == 1 {
+			return 0, errNotFound
+		}
+		return 42, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := m.GetOrLoad(context.Background(), "key", loader); !errors.Is(err, errNotFound) {
+			t.Errorf("GetOrLoad error got %v, want %v", err, errNotFound)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("Negative cache should prevent reload, loader called %d times", loads)
+	}
+
+	clock.Advance(time.Minute)
+	value, err := m.GetOrLoad(context.Background(), "key", loader)
+	if err != nil || value != 42 {
+		t.Errorf("GetOrLoad after negative ttl got (%v, %v), want (42, nil)", value, err)
+	}
+}
+
+// TestGetOrLoadContextCancel 测试等待加载时取消
+func TestGetOrLoadContextCancel(t *testing.T) {
+	m := NewStringHashMap[int]()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go m.GetOrLoad(context.Background(), "slow", func(ctx context.Context, key string) (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := m.GetOrLoad(ctx, "slow", func(ctx context.Context, key string) (int, error) {
+		t.Error("Waiter should not call loader")
+		return 0, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetOrLoad error got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// TestGetOrLoadPanic 测试加载函数panic
+func TestGetOrLoadPanic(t *testing.T) {
+	m := NewStringHashMap[int]()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Loader panic should propagate")
+			}
+		}()
+		m.GetOrLoad(context.Background(), "key", func(ctx context.Context, key string) (int, error) {
+			panic("boom")
+		})
+	}()
+
+	// panic 后可以重新加载
+	value, err := m.GetOrLoad(context.Background(), "key", func(ctx context.Context, key string) (int, error) {
+		return 7, nil
+	})
+	if err != nil || value != 7 {
+		t.Errorf("GetOrLoad after panic got (%v, %v), want (7, nil)", value, err)
+	}
+}
+
+// TestGetOrLoadLoaderCanceled 测试加载者取消不影响其他请求
+func TestGetOrLoadLoaderCanceled(t *testing.T) {
+	m := NewStringHashMap[int](WithNegativeCache(time.Minute))
+	loader := func(ctx context.Context, key string) (int, error) {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(20 * time.Millisecond):
+			return 42, nil
+		}
+	}
+
+	// 加载者的 ctx 在加载期间被取消，等待中的请求应重新加载并成功
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	leader := make(chan error, 1)
+	go func() {
+		_, err := m.GetOrLoad(ctx, "key", func(ctx context.Context, key string) (int, error) {
+			close(started)
+			return loader(ctx, key)
+		})
+		leader <- err
+	}()
+	<-started
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := m.GetOrLoad(context.Background(), "key", loader)
+		waiter <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Errorf("Leader error got %v, want %v", err, context.Canceled)
+	}
+	if err := <-waiter; err != nil {
+		t.Errorf("Waiter should reload after leader canceled, got %v", err)
+	}
+
+	// 取消错误不进入负缓存
+	m.Remove("key")
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.GetOrLoad(ctx, "key", loader); !errors.Is(err, context.Canceled) {
+		t.Errorf("Canceled load error got %v, want %v", err, context.Canceled)
+	}
+	if value, err := m.GetOrLoad(context.Background(), "key", loader); err != nil || value != 42 {
+		t.Errorf("GetOrLoad after canceled load got (%v, %v), want (42, nil)", value, err)
+	}
+}
@@ -0,0 +1,190 @@
+package cmap
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+)
+
+// MemoryStore 基于内存的 Store 实现，主要用于测试
+type MemoryStore[K cmp.Ordered, V any] struct {
+	mu    sync.RWMutex
+	items map[K]V
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore[K cmp.Ordered, V any]() *MemoryStore[K, V] {
+	return &MemoryStore[K, V]{items: make(map[K]V)}
+}
+
+// Load 读取键
+func (s *MemoryStore[K, V]) Load(_ context.Context, key K) (value V, found bool, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, found = s.items[key]
+	return
+}
+
+// Save 写入键值对
+func (s *MemoryStore[K, V]) Save(_ context.Context, key K, value V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.items[key] = value
+	return nil
+}
+
+// Delete 删除键
+func (s *MemoryStore[K, V]) Delete(_ context.Context, key K) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.items, key)
+	return nil
+}
+
+// LoadBatch 批量读取
+func (s *MemoryStore[K, V]) LoadBatch(_ context.Context, keys []K) (map[K]V, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, ok := s.items[key]; ok {
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+// SaveBatch 批量写入
+func (s *MemoryStore[K, V]) SaveBatch(_ context.Context, items map[K]V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, value := range items {
+		s.items[key] = value
+	}
+	return nil
+}
+
+// DeleteBatch 批量删除
+func (s *MemoryStore[K, V]) DeleteBatch(_ context.Context, keys []K) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.items, key)
+	}
+	return nil
+}
+
+// Len 返回存储的键数量
+func (s *MemoryStore[K, V]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.items)
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// FileStore 基于文件的 Store 实现
+//
+// 数据全部保存在内存中，每次写入后按键排序、使用指定的序列化器写入整个文件，文件格式与 SaveToFile 一致。
+// 适合数据量较小的场景或测试。
+type FileStore[K cmp.Ordered, V any] struct {
+	MemoryStore[K, V]
+	filename   string
+	serializer *SerializerFunc
+}
+
+// NewFileStore 创建文件存储，文件已存在时加载其中的数据
+func NewFileStore[K cmp.Ordered, V any](filename string, serializer *SerializerFunc) (*FileStore[K, V], error) {
+	if filename == "" {
+		return nil, fmt.Errorf("filename cannot be empty")
+	}
+	if serializer == nil {
+		serializer = JsonSerializer()
+	}
+
+	s := &FileStore[K, V]{
+		MemoryStore: MemoryStore[K, V]{items: make(map[K]V)},
+		filename:    filename,
+		serializer:  serializer,
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
+	}
+	if len(data) > 0 {
+		var serializableData SerializableData[K, V]
+		if err = serializer.Unmarshal(data, &serializableData); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal data from %s: %w", filename, err)
+		}
+		for _, tuple := range serializableData.Items {
+			s.items[tuple.Key] = tuple.Value
+		}
+	}
+	return s, nil
+}
+
+// Save 写入键值对
+func (s *FileStore[K, V]) Save(_ context.Context, key K, value V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.items[key] = value
+	return s.persist()
+}
+
+// Delete 删除键
+func (s *FileStore[K, V]) Delete(_ context.Context, key K) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.items, key)
+	return s.persist()
+}
+
+// SaveBatch 批量写入
+func (s *FileStore[K, V]) SaveBatch(_ context.Context, items map[K]V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, value := range items {
+		s.items[key] = value
+	}
+	return s.persist()
+}
+
+// DeleteBatch 批量删除
+func (s *FileStore[K, V]) DeleteBatch(_ context.Context, keys []K) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.items, key)
+	}
+	return s.persist()
+}
+
+// persist 将所有数据写入文件，调用方需持有写锁
+func (s *FileStore[K, V]) persist() error {
+	items := make([]Tuple[K, V], 0, len(s.items))
+	for key, value := range s.items {
+		items = append(items, Tuple[K, V]{Key: key, Value: value})
+	}
+	slices.SortFunc(items, compareTupleKey[K, V])
+
+	data, err := s.serializer.Marshal(SerializableData[K, V]{Items: items})
+	if err != nil {
+		return fmt.Errorf("failed to marshal data: %w", err)
+	}
+	return writeFile(s.filename, data)
+}
@@ -0,0 +1,88 @@
+package cmap
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestMemoryStore 测试内存存储
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore[string, int]()
+
+	_ = s.Save(ctx, "a", 1)
+	_ = s.SaveBatch(ctx, map[string]int{"b": 2, "c": 3})
+	if value, found, err := s.Load(ctx, "a"); err != nil || !found || value != 1 {
+		t.Errorf("Load got (%v, %v, %v), want (1, true, nil)", value, found, err)
+	}
+
+	items, _ := s.LoadBatch(ctx, []string{"b", "c", "d"})
+	if len(items) != 2 || items["b"] != 2 || items["c"] != 3 {
+		t.Errorf("LoadBatch got %v", items)
+	}
+
+	_ = s.Delete(ctx, "a")
+	_ = s.DeleteBatch(ctx, []string{"b", "d"})
+	if s.Len() != 1 {
+		t.Errorf("Len got %d, want 1", s.Len())
+	}
+}
+
+// TestFileStore 测试文件存储
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	for _, serializer := range []*SerializerFunc{JsonSerializer(), GobSerializer()} {
+		t.Run(serializer.Name(), func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "store."+serializer.Name())
+			s, err := NewFileStore[string, int](filename, serializer)
+			if err != nil {
+				t.Fatalf("NewFileStore failed: %v", err)
+			}
+			_ = s.SaveBatch(ctx, map[string]int{"a": 1, "b": 2, "c": 3})
+			_ = s.Delete(ctx, "b")
+
+			// 重新打开文件
+			reopened, err := NewFileStore[string, int](filename, serializer)
+			if err != nil {
+				t.Fatalf("Reopen failed: %v", err)
+			}
+			if reopened.Len() != 2 {
+				t.Errorf("Reopened store size %d, want 2", reopened.Len())
+			}
+			if value, found, _ := reopened.Load(ctx, "c"); !found || value != 3 {
+				t.Errorf("Reopened store got (%v, %v), want (3, true)", value, found)
+			}
+
+			// 文件格式与 SaveToFile 一致
+			m := NewStringHashMap[int](WithSerializer(serializer))
+			if err = m.LoadFromFile(filename); err != nil {
+				t.Fatalf("LoadFromFile failed: %v", err)
+			}
+			if m.Size() != 2 {
+				t.Errorf("LoadFromFile size %d, want 2", m.Size())
+			}
+		})
+	}
+}
+
+// TestFileStoreWithMap 测试映射写入文件存储
+func TestFileStoreWithMap(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "store.json")
+	s, err := NewFileStore[string, int](filename, nil)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+
+	m := NewStringHashMap[int](WithStore[string, int](s), WithWriteBehind(0, 0))
+	m.Put("a", 1)
+	m.Put("b", 2)
+	if err = m.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	reopened, _ := NewFileStore[string, int](filename, nil)
+	if reopened.Len() != 2 {
+		t.Errorf("Store file should contain 2 keys, got %d", reopened.Len())
+	}
+}
@@ -0,0 +1,96 @@
+package cmap
+
+import (
+	"fmt"
+)
+
+// 本文件封装分片对底层数据的访问：使用内置map（New、NewNativeMap）时直接读写 native，
+// 热路径上没有 maps.Map 的接口调用；其他构造函数创建的分片通过 maps.Map 访问。
+
+// find 查找底层数据中的键，包括已过期但尚未删除的键，调用方需持有分片锁
+func (sh *shard[K, V]) find(key K) (value V, found bool) {
+	if sh.native != nil {
+		value, found = sh.native[key]
+		return
+	}
+	return sh.m.Get(key)
+}
+
+// store 写入底层数据，调用方需持有分片写锁
+func (sh *shard[K, V]) store(key K, value V) {
+	if sh.native != nil {
+		sh.native[key] = value
+		return
+	}
+	sh.m.Put(key, value)
+}
+
+// erase 从底层数据中删除键，调用方需持有分片写锁
+func (sh *shard[K, V]) erase(key K) {
+	if sh.native != nil {
+		delete(sh.native, key)
+		return
+	}
+	sh.m.Remove(key)
+}
+
+// entries 返回底层数据中的键数量，包括已过期但尚未删除的键，调用方需持有分片锁
+func (sh *shard[K, V]) entries() int {
+	if sh.native != nil {
+		return len(sh.native)
+	}
+	return sh.m.Size()
+}
+
+// scan 按底层数据的顺序遍历所有键值对，fn返回false时停止并返回false，调用方需持有分片锁
+func (sh *shard[K, V]) scan(fn func(key K, value V) bool) bool {
+	if sh.native != nil {
+		for key, value := range sh.native {
+			if !fn(key, value) {
+				return false
+			}
+		}
+		return true
+	}
+	return iterate(sh.m, fn)
+}
+
+// wipe 原地清空底层数据，调用方需持有分片写锁
+func (sh *shard[K, V]) wipe() {
+	if sh.native != nil {
+		clear(sh.native)
+		return
+	}
+	sh.m.Clear()
+}
+
+// fresh 换成新的空数据，原数据保持不变，调用方需持有分片写锁
+func (sh *shard[K, V]) fresh() {
+	if sh.native != nil {
+		sh.native = make(map[K]V)
+		return
+	}
+	sh.m = sh.backend()
+}
+
+// duplicate 换成原数据的副本，调用方需持有分片写锁
+func (sh *shard[K, V]) duplicate() {
+	if sh.native != nil {
+		sh.native = cloneMap(sh.native)
+		return
+	}
+	m := sh.backend()
+	iterate(sh.m, func(key K, value V) bool {
+		m.Put(key, value)
+		return true
+	})
+	sh.m = m
+}
+
+// describe 返回底层数据的字符串表示，调用方需持有分片锁
+func (sh *shard[K, V]) describe() string {
+	if sh.native != nil {
+		return fmt.Sprintf("NativeMap\n%v", sh.native)
+	}
+	return sh.m.String()
+}
@@ -1,9 +1,51 @@
 package cmap
 
+import (
+	"cmp"
+	"fmt"
+	"time"
+)
+
 // Options 创建Map的配置选项
 type Options struct {
 	ShardCount uint32
 	Serializer *SerializerFunc
+	Equal      any // 值比较函数，类型为func(V, V) bool，用于 CompareAndSwap/CompareAndDelete
+
+	InsertionOrder bool // 是否在整个映射范围内保持插入顺序
+
+	DefaultTTL      time.Duration // 默认存活时间，<=0表示永不过期
+	JanitorInterval time.Duration // 后台清理过期键的间隔，<=0表示不启动清理协程
+
+	SlidingExpiration bool             // 是否在每次成功Get后延长键的存活时间
+	Clock             func() time.Time // 自定义时钟，为nil时使用time.Now
+	OnExpire          any              // 键过期回调，类型为func(K, V)
+
+	Capacity       int // 最大键数量，按分片均分，0表示不限制
+	EvictionPolicy any // 淘汰策略工厂，类型为EvictionPolicyFactory[K]，为nil时使用LRU
+	OnEvict        any // 键淘汰回调，类型为func(K, V)
+
+	MaxCost int64 // 成本上限，所有分片共享，0表示不限制
+	Weigher any   // 成本计算函数，类型为func(K, V) int64，为nil时每个键成本为1
+
+	NegativeTTL time.Duration // GetOrLoad 加载失败结果的缓存时间，<=0表示不缓存
+
+	Store               any             // 后端存储，类型为Store[K, V]
+	WriteBehind         bool            // 是否异步批量写入Store
+	WriteBehindInterval time.Duration   // 异步写入的间隔，<=0表示只在达到批量大小或调用Flush时写入
+	WriteBehindBatch    int             // 每批写入的最大键数量，0表示不限制
+	OnStoreError        func(err error) // 写入Store失败回调
+
+	CopyOnWrite bool // Snapshot 是否使用写时复制
+	ExactSize   bool // Size、Empty 是否同时锁定所有分片计算一致的数量
+
+	ReadOptimized bool // Get 是否优先读取各分片发布的不可变视图
+
+	TxnRetries int // 乐观事务冲突时的最大重试次数，0表示使用默认值10
+
+	OnPut     any // 键写入回调，类型为func(K, V, V, bool)
+	OnRemove  any // 键删除回调，类型为func(K, V)
+	BeforePut any // 写入前校验，类型为func(K, V) error
 }
 
 // Option 配置选项函数
@@ -22,3 +64,227 @@ func WithSerializer(v *SerializerFunc) Option {
 		o.Serializer = v
 	}
 }
+
+// WithEqual 设置值比较函数
+//
+// 未设置时使用 == 比较，值类型不可比较（如切片、map）时 CompareAndSwap、CompareAndDelete 会 panic，
+// panic 前会释放分片锁，映射保持不变并可继续使用。值类型不可比较时应设置此选项。
+// fn 的值类型必须与映射一致，否则创建映射时会panic。
+func WithEqual[V any](fn func(a, b V) bool) Option {
+	return func(o *Options) {
+		o.Equal = fn
+	}
+}
+
+// WithInsertionOrder 在整个映射范围内保持插入顺序
+//
+// 每个键首次插入时分配一个全局递增序号，Keys、Values、遍历和序列化按序号合并各分片，
+// 结果与插入顺序一致；更新已存在的键不会改变其位置。通常与 NewLinkedHashMap 搭配使用，
+// 开启后优先于 TreeMap 的按键排序。
+func WithInsertionOrder() Option {
+	return func(o *Options) {
+		o.InsertionOrder = true
+	}
+}
+
+// WithDefaultTTL 设置默认存活时间，Put 等方法写入的键在ttl后过期
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.DefaultTTL = ttl
+	}
+}
+
+// WithJanitor 启动后台清理协程，每隔interval逐个分片删除已过期的键
+//
+// 清理协程会持有映射的引用，不再使用时需调用 Close 停止。
+func WithJanitor(interval time.Duration) Option {
+	return func(o *Options) {
+		o.JanitorInterval = interval
+	}
+}
+
+// WithSlidingExpiration 开启滑动过期，每次成功 Get 都会按键的TTL重新计算过期时间
+//
+// 开启后 Get 需要获取分片写锁。
+func WithSlidingExpiration() Option {
+	return func(o *Options) {
+		o.SlidingExpiration = true
+	}
+}
+
+// WithClock 设置自定义时钟，主要用于测试
+func WithClock(clock func() time.Time) Option {
+	return func(o *Options) {
+		o.Clock = clock
+	}
+}
+
+// WithOnExpire 设置键过期回调
+//
+// 回调在释放分片锁之后调用，可以安全地访问当前映射。fn 的键值类型必须与映射一致，否则创建映射时会panic。
+func WithOnExpire[K cmp.Ordered, V any](fn func(key K, value V)) Option {
+	return func(o *Options) {
+		o.OnExpire = fn
+	}
+}
+
+// WithCapacity 设置最大键数量，超出时按淘汰策略淘汰键，默认淘汰最近最少使用（LRU）的键
+//
+// 容量按分片均分，余数分给前几个分片，各分片容量之和恰好为n，每个分片独立淘汰，因此键数量不会超过n；
+// 分片数量大于n时减少到不超过n的2的幂。分片内有已过期但尚未删除的键时先删除这些键（触发 WithOnExpire），再淘汰其他键。
+// 设置容量后 Get 需要获取分片写锁以通知淘汰策略。
+func WithCapacity(n int) Option {
+	return func(o *Options) {
+		o.Capacity = n
+	}
+}
+
+// WithEvictionPolicy 设置淘汰策略，需配合 WithCapacity 使用
+//
+// 内置 NewLRUPolicy、NewLFUPolicy、NewARCPolicy，例如 WithEvictionPolicy(cmap.NewLFUPolicy[string])。
+func WithEvictionPolicy[K cmp.Ordered](factory EvictionPolicyFactory[K]) Option {
+	return func(o *Options) {
+		o.EvictionPolicy = factory
+	}
+}
+
+// WithOnEvict 设置键淘汰回调，回调在释放分片锁之后调用，可用于统计淘汰次数
+func WithOnEvict[K cmp.Ordered, V any](fn func(key K, value V)) Option {
+	return func(o *Options) {
+		o.OnEvict = fn
+	}
+}
+
+// WithMaxCost 设置成本上限（如字节数），写入时按淘汰策略淘汰其他键直到不超过上限
+//
+// 成本由 WithWeigher 计算，上限由所有分片共享：写入时先按所在分片的淘汰策略淘汰，
+// 分片内没有其他键可淘汰时再依次从其他分片淘汰，因此淘汰顺序只在分片内符合淘汰策略。
+// 正在写入的键不会被淘汰，单个成本超过上限的键会淘汰其他所有键后保留。
+func WithMaxCost(maxCost int64) Option {
+	return func(o *Options) {
+		o.MaxCost = maxCost
+	}
+}
+
+// WithWeigher 设置成本计算函数，需配合 WithMaxCost 使用
+func WithWeigher[K cmp.Ordered, V any](fn func(key K, value V) int64) Option {
+	return func(o *Options) {
+		o.Weigher = fn
+	}
+}
+
+// WithNegativeCache 缓存 GetOrLoad 的加载错误，ttl内对同一个键直接返回该错误而不再调用加载函数
+func WithNegativeCache(ttl time.Duration) Option {
+	return func(o *Options) {
+		o.NegativeTTL = ttl
+	}
+}
+
+// WithStore 设置后端存储，默认同步写入（write-through）
+//
+// Put、Remove 等写操作在持有分片锁期间写入 Store，写入失败通过 WithOnStoreError 报告。
+// GetOrLoad 的 loader 为 nil 时从 Store 读取。store 的键值类型必须与映射一致，否则创建映射时会panic。
+func WithStore[K cmp.Ordered, V any](store Store[K, V]) Option {
+	return func(o *Options) {
+		o.Store = store
+	}
+}
+
+// WithWriteBehind 异步批量写入 Store，需配合 WithStore 使用
+//
+// 写操作只记录变更，同一个键的多次变更合并为最后一次。后台协程每隔interval，或待写入的键达到batch时，
+// 分批写入 Store，每批最多batch个键。可以调用 Flush 立即写入，Close 会在退出前写入剩余的变更。
+func WithWriteBehind(interval time.Duration, batch int) Option {
+	return func(o *Options) {
+		o.WriteBehind = true
+		o.WriteBehindInterval = interval
+		o.WriteBehindBatch = batch
+	}
+}
+
+// WithOnStoreError 设置写入 Store 失败回调，回调可能在后台协程中调用
+func WithOnStoreError(fn func(err error)) Option {
+	return func(o *Options) {
+		o.OnStoreError = fn
+	}
+}
+
+// WithCopyOnWrite 开启写时复制快照
+//
+// 开启后 Snapshot 只需短暂锁定所有分片以记录引用，耗时与数据量无关；之后首次修改仍被快照引用的分片时，
+// 写操作会先复制该分片的数据。快照不再使用时应调用 Snapshot.Release，避免后续写入产生不必要的复制。
+func WithCopyOnWrite() Option {
+	return func(o *Options) {
+		o.CopyOnWrite = true
+	}
+}
+
+// WithExactSize 使 Size、Empty 同时锁定所有分片计算一致的数量
+//
+// 默认 Size 读取各分片的原子计数而不加锁，开销更小，但并发写入时结果不是某一时刻的一致数量。
+func WithExactSize() Option {
+	return func(o *Options) {
+		o.ExactSize = true
+	}
+}
+
+// WithReadOptimized 开启读优化模式，适合配置、特性开关、路由表等读远多于写的场景
+//
+// 每个分片通过原子指针发布一份不可变的只读视图，视图有效时 Get 不获取任何锁。
+// 写操作不会复制数据，只使视图失效；失效后 Get 回退到读锁，读取次数达到分片键数量时重新生成视图。
+// 开启滑动过期或淘汰策略时 Get 需要写锁，此选项不生效。
+func WithReadOptimized() Option {
+	return func(o *Options) {
+		o.ReadOptimized = true
+	}
+}
+
+// WithTxnRetries 设置 OptimisticUpdate 冲突时的最大重试次数，默认10次
+func WithTxnRetries(n int) Option {
+	return func(o *Options) {
+		o.TxnRetries = n
+	}
+}
+
+// WithOnPut 设置键写入回调，插入新键时replaced为false，更新已有键时oldValue为旧值、replaced为true
+//
+// Put、PutAll、TryPut、Compute、Swap 等所有写入路径以及 UnmarshalWith、GetOrLoad 加载的值都会触发该回调。
+// 回调在释放分片锁之后调用，可以安全地访问当前映射。
+func WithOnPut[K cmp.Ordered, V any](fn func(key K, oldValue, newValue V, replaced bool)) Option {
+	return func(o *Options) {
+		o.OnPut = fn
+	}
+}
+
+// WithOnRemove 设置键删除回调
+//
+// Remove、RemoveMultiple、LoadAndDelete、CompareAndDelete、Compute 等删除路径以及 Clear、UnmarshalWith
+// 清空映射时对每个键触发，过期和淘汰分别由 WithOnExpire、WithOnEvict 通知。回调在释放分片锁之后调用。
+func WithOnRemove[K cmp.Ordered, V any](fn func(key K, value V)) Option {
+	return func(o *Options) {
+		o.OnRemove = fn
+	}
+}
+
+// WithBeforePut 设置写入前校验，返回错误时拒绝写入
+//
+// 所有写入路径都会校验，被拒绝时映射保持不变。TryPut 返回校验错误，Put、PutAll 等没有错误返回值的方法忽略被拒绝的写入，
+// UnmarshalWith 在任一键值对被拒绝时返回错误且不修改映射。fn 可能在分片锁内调用，不得访问当前映射。
+func WithBeforePut[K cmp.Ordered, V any](fn func(key K, value V) error) Option {
+	return func(o *Options) {
+		o.BeforePut = fn
+	}
+}
+
+// hook 将Options中保存的回调转换为具体类型，类型不匹配时panic
+func hook[F any](v any) F {
+	var zero F
+	if v == nil {
+		return zero
+	}
+	fn, ok := v.(F)
+	if !ok {
+		panic(fmt.Sprintf("cmap: callback of type %T does not match %T", v, zero))
+	}
+	return fn
+}
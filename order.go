@@ -0,0 +1,54 @@
+package cmap
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/emirpasic/gods/v2/maps/linkedhashmap"
+)
+
+// sequencedTuple 带全局插入序号的键值对
+type sequencedTuple[K cmp.Ordered, V any] struct {
+	Tuple[K, V]
+	seq uint64
+}
+
+// itemsInInsertionOrder 按全局插入顺序返回所有键值对，lock为false时调用方需已持有所有分片锁
+//
+// LinkedHashMap 分片内已按插入顺序排列，直接多路归并；其他类型先在分片内按序号排序。
+func (m *Map[K, V]) itemsInInsertionOrder(lock bool) []Tuple[K, V] {
+	parts := make([][]sequencedTuple[K, V], len(m.shards))
+	for i := range m.shards {
+		sh := &m.shards[i]
+		if lock {
+			sh.mu.RLock()
+		}
+		part := make([]sequencedTuple[K, V], 0, sh.entries())
+		sh.each(func(key K, value V) bool {
+			part = append(part, sequencedTuple[K, V]{
+				Tuple: Tuple[K, V]{Key: key, Value: value},
+				seq:   sh.seqs[key],
+			})
+			return true
+		})
+		if lock {
+			sh.mu.RUnlock()
+		}
+
+		if _, linked := sh.m.(*linkedhashmap.Map[K, V]); !linked {
+			slices.SortFunc(part, func(a, b sequencedTuple[K, V]) int {
+				return cmp.Compare(a.seq, b.seq)
+			})
+		}
+		parts[i] = part
+	}
+
+	merged := mergeSorted(parts, func(a, b sequencedTuple[K, V]) bool {
+		return a.seq < b.seq
+	})
+	items := make([]Tuple[K, V], len(merged))
+	for i, item := range merged {
+		items[i] = item.Tuple
+	}
+	return items
+}
@@ -0,0 +1,116 @@
+package cmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// TestInsertionOrder 测试全局插入顺序
+func TestInsertionOrder(t *testing.T) {
+	constructors := map[string]func() *Map[string, int]{
+		"LinkedHashMap": func() *Map[string, int] {
+			return NewStringLinkedHashMap[int](WithShardCount(8), WithInsertionOrder())
+		},
+		"HashMap": func() *Map[string, int] {
+			return NewStringHashMap[int](WithShardCount(8), WithInsertionOrder())
+		},
+		"TreeMap": func() *Map[string, int] {
+			return NewStringTreeMap[int](WithShardCount(8), WithInsertionOrder())
+		},
+	}
+
+	for name, create := range constructors {
+		t.Run(name, func(t *testing.T) {
+			m := create()
+			var want []string
+			for i := 0; i < 100; i++ {
+				key := fmt.Sprintf("key-%03d", 99-i)
+				m.Put(key, i)
+				want = append(want, key)
+			}
+
+			if keys := m.Keys(); !slices.Equal(keys, want) {
+				t.Errorf("Keys should follow insertion order, got %v", keys)
+			}
+
+			values := m.Values()
+			for i, value := range values {
+				if value != i {
+					t.Fatalf("Values should follow insertion order, got %v at %d", value, i)
+				}
+			}
+
+			var ranged []string
+			m.Range(func(key string, value int) bool {
+				ranged = append(ranged, key)
+				return true
+			})
+			if !slices.Equal(ranged, want) {
+				t.Errorf("Range should follow insertion order, got %v", ranged)
+			}
+		})
+	}
+}
+
+// TestInsertionOrderUpdateAndRemove 测试更新与删除对插入顺序的影响
+func TestInsertionOrderUpdateAndRemove(t *testing.T) {
+	m := NewStringLinkedHashMap[int](WithShardCount(4), WithInsertionOrder())
+	for _, key := range []string{"c", "a", "d", "b"} {
+		m.Put(key, 0)
+	}
+
+	// 更新已存在的键不改变位置
+	m.Put("c", 1)
+	// 删除后重新插入移动到末尾
+	m.Remove("a")
+	m.Put("a", 2)
+
+	want := []string{"c", "d", "b", "a"}
+	if keys := m.Keys(); !slices.Equal(keys, want) {
+		t.Errorf("Keys got %v, want %v", keys, want)
+	}
+
+	m.Clear()
+	m.Put("z", 0)
+	m.Put("y", 0)
+	if keys := m.Keys(); !slices.Equal(keys, []string{"z", "y"}) {
+		t.Errorf("Keys after Clear got %v, want [z y]", keys)
+	}
+}
+
+// TestInsertionOrderSerialization 测试序列化保持插入顺序
+func TestInsertionOrderSerialization(t *testing.T) {
+	m := NewStringLinkedHashMap[int](WithShardCount(16), WithInsertionOrder())
+	var want []string
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("record-%d", 20-i)
+		m.Put(key, i)
+		want = append(want, key)
+	}
+
+	data, err := m.MarshalWith(JsonSerializer())
+	if err != nil {
+		t.Fatalf("MarshalWith failed: %v", err)
+	}
+
+	var parsed SerializableData[string, int]
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	for i, item := range parsed.Items {
+		if item.Key != want[i] {
+			t.Fatalf("Serialized item %d got %s, want %s", i, item.Key, want[i])
+		}
+	}
+
+	// 反序列化后顺序保持不变
+	loaded := NewStringLinkedHashMap[int](WithShardCount(16), WithInsertionOrder())
+	if err := loaded.UnmarshalWith(data, JsonSerializer()); err != nil {
+		t.Fatalf("UnmarshalWith failed: %v", err)
+	}
+	if keys := loaded.Keys(); !slices.Equal(keys, want) {
+		t.Errorf("Keys after UnmarshalWith got %v, want %v", keys, want)
+	}
+}
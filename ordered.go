@@ -0,0 +1,275 @@
+package cmap
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+
+	"github.com/emirpasic/gods/v2/maps/treemap"
+)
+
+// Min 返回最小的键及其值，映射为空时ok为false
+//
+// TreeMap 直接使用各分片红黑树的最小值，其他类型逐个扫描分片。
+func (m *Map[K, V]) Min() (key K, value V, ok bool) {
+	for i := range m.shards {
+		sh := &m.shards[i]
+		sh.mu.RLock()
+		k, v, found := sh.min()
+		sh.mu.RUnlock()
+		if found && (!ok || cmp.Less(k, key)) {
+			key, value, ok = k, v, true
+		}
+	}
+	return
+}
+
+// Max 返回最大的键及其值，映射为空时ok为false
+func (m *Map[K, V]) Max() (key K, value V, ok bool) {
+	for i := range m.shards {
+		sh := &m.shards[i]
+		sh.mu.RLock()
+		k, v, found := sh.max()
+		sh.mu.RUnlock()
+		if found && (!ok || cmp.Less(key, k)) {
+			key, value, ok = k, v, true
+		}
+	}
+	return
+}
+
+// Floor 返回小于等于key的最大键及其值，不存在时ok为false
+func (m *Map[K, V]) Floor(key K) (foundKey K, foundValue V, ok bool) {
+	for i := range m.shards {
+		sh := &m.shards[i]
+		sh.mu.RLock()
+		k, v, found := sh.floor(key)
+		sh.mu.RUnlock()
+		if found && (!ok || cmp.Less(foundKey, k)) {
+			foundKey, foundValue, ok = k, v, true
+		}
+	}
+	return
+}
+
+// Ceiling 返回大于等于key的最小键及其值，不存在时ok为false
+func (m *Map[K, V]) Ceiling(key K) (foundKey K, foundValue V, ok bool) {
+	for i := range m.shards {
+		sh := &m.shards[i]
+		sh.mu.RLock()
+		k, v, found := sh.ceiling(key)
+		sh.mu.RUnlock()
+		if found && (!ok || cmp.Less(k, foundKey)) {
+			foundKey, foundValue, ok = k, v, true
+		}
+	}
+	return
+}
+
+// RangeBetween 按键升序返回闭区间[lo, hi]内的所有键值对
+func (m *Map[K, V]) RangeBetween(lo, hi K) []Tuple[K, V] {
+	if cmp.Less(hi, lo) {
+		return nil
+	}
+
+	parts := make([][]Tuple[K, V], 0, len(m.shards))
+	for i := range m.shards {
+		var part []Tuple[K, V]
+		sh := &m.shards[i]
+		sorted := sh.sorted()
+		sh.mu.RLock()
+		sh.each(func(key K, value V) bool {
+			if cmp.Less(hi, key) {
+				// TreeMap 分片有序，超过上界即可停止
+				return !sorted
+			}
+			if !cmp.Less(key, lo) {
+				part = append(part, Tuple[K, V]{Key: key, Value: value})
+			}
+			return true
+		})
+		sh.mu.RUnlock()
+
+		if !sorted {
+			slices.SortFunc(part, compareTupleKey[K, V])
+		}
+		parts = append(parts, part)
+	}
+	return mergeSorted(parts, lessTupleKey[K, V])
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// sorted 检查分片是否按键有序存储
+func (sh *shard[K, V]) sorted() bool {
+	_, ok := sh.m.(*treemap.Map[K, V])
+	return ok
+}
+
+// tree 返回分片底层的TreeMap，分片中存在可能过期的键时返回false，此时需要逐个扫描以跳过过期键
+func (sh *shard[K, V]) tree() (*treemap.Map[K, V], bool) {
+	if len(sh.expires) > 0 {
+		return nil, false
+	}
+	tm, ok := sh.m.(*treemap.Map[K, V])
+	return tm, ok
+}
+
+// min 返回分片内最小的键值对，调用方需持有分片锁
+func (sh *shard[K, V]) min() (key K, value V, ok bool) {
+	if tm, isTree := sh.tree(); isTree {
+		return tm.Min()
+	}
+	sh.each(func(k K, v V) bool {
+		if !ok || cmp.Less(k, key) {
+			key, value, ok = k, v, true
+		}
+		return true
+	})
+	return
+}
+
+// max 返回分片内最大的键值对，调用方需持有分片锁
+func (sh *shard[K, V]) max() (key K, value V, ok bool) {
+	if tm, isTree := sh.tree(); isTree {
+		return tm.Max()
+	}
+	sh.each(func(k K, v V) bool {
+		if !ok || cmp.Less(key, k) {
+			key, value, ok = k, v, true
+		}
+		return true
+	})
+	return
+}
+
+// floor 返回分片内小于等于target的最大键值对，调用方需持有分片锁
+func (sh *shard[K, V]) floor(target K) (key K, value V, ok bool) {
+	if tm, isTree := sh.tree(); isTree {
+		return tm.Floor(target)
+	}
+	sh.each(func(k K, v V) bool {
+		if !cmp.Less(target, k) && (!ok || cmp.Less(key, k)) {
+			key, value, ok = k, v, true
+		}
+		return true
+	})
+	return
+}
+
+// ceiling 返回分片内大于等于target的最小键值对，调用方需持有分片锁
+func (sh *shard[K, V]) ceiling(target K) (key K, value V, ok bool) {
+	if tm, isTree := sh.tree(); isTree {
+		return tm.Ceiling(target)
+	}
+	sh.each(func(k K, v V) bool {
+		if !cmp.Less(k, target) && (!ok || cmp.Less(k, key)) {
+			key, value, ok = k, v, true
+		}
+		return true
+	})
+	return
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// merged 检查遍历时是否需要归并各分片以保证全局顺序
+func (m *Map[K, V]) merged() bool {
+	return m.sorted || m.order
+}
+
+// items 按映射的遍历顺序返回所有键值对，逐个分片加读锁复制
+func (m *Map[K, V]) items() []Tuple[K, V] {
+	return m.collect(true)
+}
+
+// collect 按映射的遍历顺序返回所有键值对，lock为false时调用方需已持有所有分片锁
+//
+// 开启 WithInsertionOrder 时按插入序号归并；TreeMap 对各分片结果做多路归并，保证全局按键有序；
+// 其他类型按分片顺序拼接。
+func (m *Map[K, V]) collect(lock bool) []Tuple[K, V] {
+	if m.order {
+		return m.itemsInInsertionOrder(lock)
+	}
+
+	parts := make([][]Tuple[K, V], len(m.shards))
+	total := 0
+	for i := range m.shards {
+		sh := &m.shards[i]
+		if lock {
+			sh.mu.RLock()
+		}
+		part := make([]Tuple[K, V], 0, sh.entries())
+		sh.each(func(key K, value V) bool {
+			part = append(part, Tuple[K, V]{Key: key, Value: value})
+			return true
+		})
+		if lock {
+			sh.mu.RUnlock()
+		}
+		parts[i] = part
+		total += len(part)
+	}
+
+	if m.sorted {
+		return mergeSorted(parts, lessTupleKey[K, V])
+	}
+
+	items := make([]Tuple[K, V], 0, total)
+	for _, part := range parts {
+		items = append(items, part...)
+	}
+	return items
+}
+
+func lessTupleKey[K cmp.Ordered, V any](a, b Tuple[K, V]) bool {
+	return cmp.Less(a.Key, b.Key)
+}
+
+func compareTupleKey[K cmp.Ordered, V any](a, b Tuple[K, V]) int {
+	return cmp.Compare(a.Key, b.Key)
+}
+
+// mergeSorted 多路归并若干各自有序的切片
+func mergeSorted[T any](parts [][]T, less func(a, b T) bool) []T {
+	total := 0
+	h := &mergeHeap[T]{less: less}
+	for _, part := range parts {
+		if len(part) > 0 {
+			h.parts = append(h.parts, part)
+			total += len(part)
+		}
+	}
+	heap.Init(h)
+
+	result := make([]T, 0, total)
+	for h.Len() > 0 {
+		part := h.parts[0]
+		result = append(result, part[0])
+		if len(part) > 1 {
+			h.parts[0] = part[1:]
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return result
+}
+
+// mergeHeap 以各切片首元素排序的小顶堆
+type mergeHeap[T any] struct {
+	parts [][]T
+	less  func(a, b T) bool
+}
+
+func (h *mergeHeap[T]) Len() int           { return len(h.parts) }
+func (h *mergeHeap[T]) Less(i, j int) bool { return h.less(h.parts[i][0], h.parts[j][0]) }
+func (h *mergeHeap[T]) Swap(i, j int)      { h.parts[i], h.parts[j] = h.parts[j], h.parts[i] }
+func (h *mergeHeap[T]) Push(x any)         { h.parts = append(h.parts, x.([]T)) }
+
+func (h *mergeHeap[T]) Pop() any {
+	n := len(h.parts)
+	x := h.parts[n-1]
+	h.parts = h.parts[:n-1]
+	return x
+}
@@ -0,0 +1,151 @@
+package cmap
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestTreeMapGlobalOrder 测试TreeMap全局有序
+func TestTreeMapGlobalOrder(t *testing.T) {
+	m := NewIntTreeMap[int](WithShardCount(8))
+	for _, k := range []int{42, 7, 99, 1, 63, 18, 5, 77, 30, 12} {
+		m.Put(k, k*10)
+	}
+
+	keys := m.Keys()
+	if !slices.IsSorted(keys) {
+		t.Errorf("TreeMap Keys should be globally sorted, got %v", keys)
+	}
+
+	values := m.Values()
+	for i, key := range keys {
+		if values[i] != key*10 {
+			t.Errorf("Values should follow key order, got %v at %d, want %v", values[i], i, key*10)
+		}
+	}
+
+	var ranged []int
+	m.Range(func(key int, value int) bool {
+		ranged = append(ranged, key)
+		return true
+	})
+	if !slices.Equal(ranged, keys) {
+		t.Errorf("Range order %v does not match Keys %v", ranged, keys)
+	}
+
+	var safe []int
+	m.RangeSafe(func(key int, value int) bool {
+		safe = append(safe, key)
+		return true
+	})
+	if !slices.Equal(safe, keys) {
+		t.Errorf("RangeSafe order %v does not match Keys %v", safe, keys)
+	}
+}
+
+// TestTreeMapSerializationOrder 测试TreeMap序列化有序
+func TestTreeMapSerializationOrder(t *testing.T) {
+	m := NewStringTreeMap[int](WithShardCount(4))
+	m.PutAll(map[string]int{"d": 4, "b": 2, "a": 1, "c": 3, "e": 5})
+
+	data, err := m.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+
+	want := `{"items":[{"key":"a","value":1},{"key":"b","value":2},{"key":"c","value":3},{"key":"d","value":4},{"key":"e","value":5}]}`
+	if string(data) != want {
+		t.Errorf("MarshalJSON got %s, want %s", data, want)
+	}
+}
+
+// TestMinMaxFloorCeiling 测试Min/Max/Floor/Ceiling
+func TestMinMaxFloorCeiling(t *testing.T) {
+	constructors := map[string]func() *Map[int, string]{
+		"TreeMap": func() *Map[int, string] { return NewIntTreeMap[string](WithShardCount(8)) },
+		"HashMap": func() *Map[int, string] { return NewIntHashMap[string](WithShardCount(8)) },
+	}
+
+	for name, create := range constructors {
+		t.Run(name, func(t *testing.T) {
+			m := create()
+			if _, _, ok := m.Min(); ok {
+				t.Error("Min on empty map should return false")
+			}
+			if _, _, ok := m.Max(); ok {
+				t.Error("Max on empty map should return false")
+			}
+
+			for _, k := range []int{10, 20, 30, 40, 50} {
+				m.Put(k, "v")
+			}
+
+			if k, _, ok := m.Min(); !ok || k != 10 {
+				t.Errorf("Min got %v, want 10", k)
+			}
+			if k, _, ok := m.Max(); !ok || k != 50 {
+				t.Errorf("Max got %v, want 50", k)
+			}
+			if k, _, ok := m.Floor(35); !ok || k != 30 {
+				t.Errorf("Floor(35) got %v, want 30", k)
+			}
+			if k, _, ok := m.Floor(40); !ok || k != 40 {
+				t.Errorf("Floor(40) got %v, want 40", k)
+			}
+			if _, _, ok := m.Floor(5); ok {
+				t.Error("Floor(5) should not be found")
+			}
+			if k, _, ok := m.Ceiling(35); !ok || k != 40 {
+				t.Errorf("Ceiling(35) got %v, want 40", k)
+			}
+			if _, _, ok := m.Ceiling(55); ok {
+				t.Error("Ceiling(55) should not be found")
+			}
+		})
+	}
+}
+
+// TestRangeBetween 测试区间查询
+func TestRangeBetween(t *testing.T) {
+	constructors := map[string]func() *Map[int, int]{
+		"TreeMap": func() *Map[int, int] { return NewIntTreeMap[int](WithShardCount(8)) },
+		"HashMap": func() *Map[int, int] { return NewIntHashMap[int](WithShardCount(8)) },
+	}
+
+	for name, create := range constructors {
+		t.Run(name, func(t *testing.T) {
+			m := create()
+			for i := 0; i < 100; i++ {
+				m.Put(i, i)
+			}
+
+			items := m.RangeBetween(25, 34)
+			if len(items) != 10 {
+				t.Fatalf("RangeBetween returned %d items, want 10", len(items))
+			}
+			for i, item := range items {
+				if item.Key != 25+i || item.Value != 25+i {
+					t.Errorf("RangeBetween item %d got %v, want key %d", i, item, 25+i)
+				}
+			}
+
+			if items := m.RangeBetween(50, 10); len(items) != 0 {
+				t.Errorf("RangeBetween with lo > hi should be empty, got %v", items)
+			}
+		})
+	}
+}
+
+// TestMergeSorted 测试多路归并
+func TestMergeSorted(t *testing.T) {
+	parts := [][]int{{1, 4, 7}, {}, {2, 5, 8}, {3, 6, 9, 10}}
+	got := mergeSorted(parts, func(a, b int) bool { return a < b })
+	want := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	if !slices.Equal(got, want) {
+		t.Errorf("mergeSorted got %v, want %v", got, want)
+	}
+
+	if got := mergeSorted[int](nil, func(a, b int) bool { return a < b }); len(got) != 0 {
+		t.Errorf("mergeSorted of nothing should be empty, got %v", got)
+	}
+}
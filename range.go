@@ -0,0 +1,61 @@
+package cmap
+
+import "cmp"
+
+// Range 遍历所有键值对，fn返回false时停止遍历
+//
+// 逐个分片在读锁内调用 fn，不会复制整个映射。fn 执行期间持有分片读锁，不得在 fn 中访问当前映射，
+// 包括 Get 等读操作：开启滑动过期、容量或成本上限后 Get 需要获取分片写锁，同样会死锁。
+// 需要在遍历时读写当前映射请使用 RangeSafe。
+// TreeMap 或开启 WithInsertionOrder 时需要全局有序，会先复制并归并所有分片，再在锁外调用 fn。
+func (m *Map[K, V]) Range(fn func(key K, value V) bool) {
+	if m.merged() {
+		rangeItems(m.items(), fn)
+		return
+	}
+
+	for i := range m.shards {
+		m.shards[i].mu.RLock()
+		next := m.shards[i].each(fn)
+		m.shards[i].mu.RUnlock()
+		if !next {
+			return
+		}
+	}
+}
+
+// RangeSafe 遍历所有键值对，fn返回false时停止遍历
+//
+// 每个分片的键值对先在读锁内复制出来，再在锁外调用 fn，因此 fn 中可以安全地读写当前映射。
+// fn 看到的是各分片复制时刻的数据，遍历过程中发生的修改可能不可见。
+func (m *Map[K, V]) RangeSafe(fn func(key K, value V) bool) {
+	if m.merged() {
+		rangeItems(m.items(), fn)
+		return
+	}
+
+	var items []Tuple[K, V]
+	for i := range m.shards {
+		items = items[:0]
+		m.shards[i].mu.RLock()
+		m.shards[i].each(func(key K, value V) bool {
+			items = append(items, Tuple[K, V]{Key: key, Value: value})
+			return true
+		})
+		m.shards[i].mu.RUnlock()
+
+		if !rangeItems(items, fn) {
+			return
+		}
+	}
+}
+
+// rangeItems 依次对键值对调用fn，fn返回false时停止并返回false
+func rangeItems[K cmp.Ordered, V any](items []Tuple[K, V], fn func(key K, value V) bool) bool {
+	for _, item := range items {
+		if !fn(item.Key, item.Value) {
+			return false
+		}
+	}
+	return true
+}
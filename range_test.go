@@ -0,0 +1,84 @@
+package cmap
+
+import (
+	"testing"
+)
+
+// TestRange 测试Range遍历
+func TestRange(t *testing.T) {
+	constructors := map[string]func() *Map[string, int]{
+		"HashMap":       func() *Map[string, int] { return NewStringHashMap[int]() },
+		"TreeMap":       func() *Map[string, int] { return NewStringTreeMap[int]() },
+		"LinkedHashMap": func() *Map[string, int] { return NewStringLinkedHashMap[int]() },
+	}
+
+	for name, create := range constructors {
+		t.Run(name, func(t *testing.T) {
+			m := create()
+			data := map[string]int{"a": 1, "b": 2, "c": 3, "d": 4}
+			m.PutAll(data)
+
+			seen := make(map[string]int)
+			m.Range(func(key string, value int) bool {
+				seen[key] = value
+				return true
+			})
+
+			if len(seen) != len(data) {
+				t.Errorf("Range visited %d items, want %d", len(seen), len(data))
+			}
+			for key, value := range data {
+				if seen[key] != value {
+					t.Errorf("Range got %v for key %s, want %v", seen[key], key, value)
+				}
+			}
+		})
+	}
+}
+
+// TestRangeEarlyStop 测试Range提前终止
+func TestRangeEarlyStop(t *testing.T) {
+	m := NewStringHashMap[int](WithShardCount(4))
+	for i := 0; i < 100; i++ {
+		m.Put("key"+string(rune('a'+i%26))+string(rune('a'+i/26)), i)
+	}
+
+	count := 0
+	m.Range(func(key string, value int) bool {
+		count++
+		return count < 5
+	})
+	if count != 5 {
+		t.Errorf("Range should stop after 5 items, visited %d", count)
+	}
+
+	count = 0
+	m.RangeSafe(func(key string, value int) bool {
+		count++
+		return count < 5
+	})
+	if count != 5 {
+		t.Errorf("RangeSafe should stop after 5 items, visited %d", count)
+	}
+}
+
+// TestRangeSafeMutation 测试RangeSafe中修改映射
+func TestRangeSafeMutation(t *testing.T) {
+	m := NewStringHashMap[int]()
+	m.PutAll(map[string]int{"a": 1, "b": 2, "c": 3})
+
+	m.RangeSafe(func(key string, value int) bool {
+		m.Put(key, value*10)
+		if key == "b" {
+			m.Remove(key)
+		}
+		return true
+	})
+
+	if _, ok := m.Get("b"); ok {
+		t.Error("RangeSafe callback should be able to remove keys")
+	}
+	if value, _ := m.Get("a"); value != 10 {
+		t.Errorf("RangeSafe callback should be able to update keys, got %v, want 10", value)
+	}
+}
@@ -33,43 +33,39 @@ func (m *Map[K, V]) getJSONSerializer() *SerializerFunc {
 	return JsonSerializer()
 }
 
-// MarshalWith 使用指定序列化器进行序列化
+// MarshalWith 使用指定序列化器进行序列化，序列化的是调用时刻所有分片的一致快照（见 Snapshot）
 func (m *Map[K, V]) MarshalWith(serializer *SerializerFunc) ([]byte, error) {
-	items := make([]Tuple[K, V], 0, m.Size())
-	for i := range m.shards {
-		m.shards[i].mu.RLock()
-		keys := m.shards[i].m.Keys()
-		for _, key := range keys {
-			value, _ := m.shards[i].m.Get(key)
-			items = append(items, Tuple[K, V]{Key: key, Value: value})
-		}
-		m.shards[i].mu.RUnlock()
-	}
-
-	data := SerializableData[K, V]{Items: items}
-
-	return serializer.Marshal(data)
+	s := m.Snapshot()
+	defer s.Release()
+	return s.MarshalWith(serializer)
 }
 
 // UnmarshalWith 使用指定序列化器进行反序列化
+//
+// 加载的键值对与 LoadFromStore 相同不会写回 Store，会触发 OnPut 回调和订阅事件。
 func (m *Map[K, V]) UnmarshalWith(data []byte, serializer *SerializerFunc) error {
 	var serializableData SerializableData[K, V]
 	if err := serializer.Unmarshal(data, &serializableData); err != nil {
 		return err
 	}
 
+	// 先校验所有键值对，任一被拒绝时不修改映射
+	for _, tuple := range serializableData.Items {
+		if err := m.check(tuple.Key, tuple.Value); err != nil {
+			return err
+		}
+	}
+
 	// 清空现有数据
 	m.Clear()
 
-	// 加载数据
+	// 加载数据，与 LoadFromStore 相同不写回 Store
 	for _, tuple := range serializableData.Items {
-		m.Put(tuple.Key, tuple.Value)
+		m.restore(tuple.Key, tuple.Value)
 	}
 
 	// 加载完成后标记为未修改
-	m.mu.Lock()
-	m.dirty = false
-	m.mu.Unlock()
+	m.markSaved(m.versions())
 
 	return nil
 }
@@ -0,0 +1,282 @@
+package cmap
+
+import (
+	"cmp"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Snapshot 映射在某一时刻的只读视图
+//
+// 快照创建时同时锁定所有分片，因此是一致的时间切面：跨分片的修改要么全部可见，要么全部不可见。
+// 快照与映射相互独立，之后对映射的修改不会影响快照。快照只复制值本身，值为指针等引用类型时与映射共享其指向的数据。
+type Snapshot[K cmp.Ordered, V any] struct {
+	frozen *Map[K, V]      // 写时复制模式下冻结的分片数据，否则为nil
+	refs   []*atomic.Int32 // 写时复制模式下各分片数据的引用计数
+	free   sync.Once       // 保证只释放一次引用
+
+	load       sync.Once
+	items      []Tuple[K, V] // 按映射的遍历顺序排列的键值对，写时复制模式下首次使用时生成
+	index      map[K]int     // 键在items中的位置，仅复制模式使用
+	serializer *SerializerFunc
+	versions   []uint64 // 创建快照时各分片的版本号
+}
+
+// Snapshot 创建映射的一致快照
+//
+// 默认按分片顺序获取所有分片的读锁后复制数据，复制期间所有写操作都会阻塞。
+// 开启 WithCopyOnWrite 时只记录各分片数据的引用，不复制数据，使用完毕后应调用 Release。
+// 已过期的键不包含在快照中。
+func (m *Map[K, V]) Snapshot() *Snapshot[K, V] {
+	if m.opts.CopyOnWrite {
+		return m.snapshotCopyOnWrite()
+	}
+
+	m.rlockAll()
+	items := m.collect(false)
+	versions := m.versions()
+	m.runlockAll()
+
+	index := make(map[K]int, len(items))
+	for i, item := range items {
+		index[item.Key] = i
+	}
+	return &Snapshot[K, V]{items: items, index: index, serializer: m.opts.Serializer, versions: versions}
+}
+
+// snapshotCopyOnWrite 创建写时复制快照，只在锁定所有分片期间记录各分片数据的引用
+func (m *Map[K, V]) snapshotCopyOnWrite() *Snapshot[K, V] {
+	// 冻结的分片使用固定时钟，过期判断以快照时刻为准
+	opts := *m.opts
+	frozen := &Map[K, V]{
+		shards: make([]shard[K, V], len(m.shards)),
+		mask:   m.mask,
+		sorted: m.sorted,
+		order:  m.order,
+		hasher: m.hasher,
+		opts:   &opts,
+	}
+	refs := make([]*atomic.Int32, len(m.shards))
+
+	for i := range m.shards {
+		m.shards[i].mu.Lock()
+	}
+	now := m.opts.now()
+	opts.Clock = func() time.Time { return time.Unix(0, now) }
+	for i := range m.shards {
+		sh := &m.shards[i]
+		if sh.refs == nil {
+			sh.refs = &atomic.Int32{}
+		}
+		sh.refs.Add(1)
+		refs[i] = sh.refs
+		frozen.shards[i] = shard[K, V]{m: sh.m, native: sh.native, opts: &opts, seqs: sh.seqs, expires: sh.expires}
+	}
+	versions := m.versions()
+	for i := range m.shards {
+		m.shards[i].mu.Unlock()
+	}
+
+	return &Snapshot[K, V]{frozen: frozen, refs: refs, serializer: m.opts.Serializer, versions: versions}
+}
+
+// Clone 创建与当前映射相互独立的副本，使用相同的配置和底层Map类型
+//
+// 复制时持有所有分片的读锁，副本与 Snapshot 一样是一致的时间切面，并保留键的过期时间和插入顺序。
+// 淘汰策略的访问记录不会复制；副本会按配置启动自己的后台协程，不再使用时需调用 Close。
+// 复制本身不会触发回调、订阅事件或写入 Store。
+//
+// 注意：副本不继承 WithStore、WithWriteBehind 和 WithOnStoreError，对副本的写入不会写入原映射的 Store，
+// GetOrLoad 的 loader 为 nil 时也不会从 Store 读取；WithOnPut 等回调仍然共享，副本的变更同样会触发这些回调。
+func (m *Map[K, V]) Clone() *Map[K, V] {
+	opts := *m.opts
+	opts.Store = nil
+	opts.WriteBehind = false
+	opts.WriteBehindInterval = 0
+	opts.WriteBehindBatch = 0
+	opts.OnStoreError = nil
+	c := newMap(m.backend, &opts)
+
+	m.rlockAll()
+	for i := range m.shards {
+		src, dst := &m.shards[i], &c.shards[i]
+		// 副本的后台清理协程已经启动，写入副本分片同样需要持有其写锁
+		dst.mu.Lock()
+		src.each(func(key K, value V) bool {
+			dst.set(key, value, 0)
+			if e, ok := src.expires[key]; ok {
+				dst.expires[key] = e
+				dst.size.lower(e.deadline)
+			}
+			if dst.seq != nil {
+				dst.seqs[key] = src.seqs[key]
+			}
+			return true
+		})
+		dst.recount()
+
+		// 逐个分片保留修改标记
+		if src.version.Load() == src.saved.Load() {
+			dst.saved.Store(dst.version.Load())
+		} else if dst.version.Load() == dst.saved.Load() {
+			dst.version.Add(1)
+		}
+		dst.mu.Unlock()
+	}
+	if c.order {
+		c.shards[0].seq.Store(m.shards[0].seq.Load())
+	}
+	m.runlockAll()
+	return c
+}
+
+// rlockAll 按分片顺序获取所有分片的读锁
+func (m *Map[K, V]) rlockAll() {
+	for i := range m.shards {
+		m.shards[i].mu.RLock()
+	}
+}
+
+// runlockAll 释放所有分片的读锁
+func (m *Map[K, V]) runlockAll() {
+	for i := range m.shards {
+		m.shards[i].mu.RUnlock()
+	}
+}
+
+// own 分片数据仍被快照引用时先复制一份，保证之后的修改不影响快照，调用方需持有分片写锁
+func (sh *shard[K, V]) own() {
+	if !sh.shared() {
+		sh.refs = nil
+		return
+	}
+
+	sh.duplicate()
+	sh.expires = cloneMap(sh.expires)
+	if sh.seqs != nil {
+		sh.seqs = cloneMap(sh.seqs)
+	}
+	sh.refs = nil
+}
+
+// shared 检查分片数据是否仍被快照引用，调用方需持有分片锁
+func (sh *shard[K, V]) shared() bool {
+	return sh.refs != nil && sh.refs.Load() > 0
+}
+
+// cloneMap 复制内置map
+func cloneMap[K comparable, T any](src map[K]T) map[K]T {
+	dst := make(map[K]T, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// Release 释放快照对映射数据的引用，之后不得再使用该快照，可重复调用
+//
+// 仅对 WithCopyOnWrite 模式有意义：释放后写操作不再需要为该快照复制分片数据。
+func (s *Snapshot[K, V]) Release() {
+	s.free.Do(func() {
+		for _, ref := range s.refs {
+			ref.Add(-1)
+		}
+	})
+}
+
+// Get 获取值
+func (s *Snapshot[K, V]) Get(key K) (value V, found bool) {
+	if s.frozen != nil {
+		return s.frozen.getShard(key).get(key)
+	}
+
+	i, found := s.index[key]
+	if found {
+		value = s.items[i].Value
+	}
+	return
+}
+
+// Size 获取大小
+func (s *Snapshot[K, V]) Size() int {
+	if s.frozen != nil {
+		size := 0
+		for i := range s.frozen.shards {
+			size += s.frozen.shards[i].len()
+		}
+		return size
+	}
+	return len(s.items)
+}
+
+// Empty 检查是否为空
+func (s *Snapshot[K, V]) Empty() bool {
+	return s.Size() == 0
+}
+
+// Keys 获取所有键，顺序与创建快照时映射的 Keys 一致
+func (s *Snapshot[K, V]) Keys() []K {
+	items := s.tuples()
+	keys := make([]K, len(items))
+	for i, item := range items {
+		keys[i] = item.Key
+	}
+	return keys
+}
+
+// Values 获取所有值，顺序与 Keys 一致
+func (s *Snapshot[K, V]) Values() []V {
+	items := s.tuples()
+	values := make([]V, len(items))
+	for i, item := range items {
+		values[i] = item.Value
+	}
+	return values
+}
+
+// Items 获取所有键值对，顺序与 Keys 一致
+func (s *Snapshot[K, V]) Items() []Tuple[K, V] {
+	items := make([]Tuple[K, V], len(s.tuples()))
+	copy(items, s.tuples())
+	return items
+}
+
+// Range 遍历所有键值对，fn返回false时停止遍历
+func (s *Snapshot[K, V]) Range(fn func(key K, value V) bool) {
+	rangeItems(s.tuples(), fn)
+}
+
+// MarshalWith 使用指定序列化器进行序列化，格式与 Map.MarshalWith 一致
+func (s *Snapshot[K, V]) MarshalWith(serializer *SerializerFunc) ([]byte, error) {
+	return serializer.Marshal(SerializableData[K, V]{Items: s.tuples()})
+}
+
+// SaveToFile 使用映射配置的序列化器保存到文件，可通过 Map.LoadFromFile 加载
+func (s *Snapshot[K, V]) SaveToFile(filename string) error {
+	if filename == "" {
+		return fmt.Errorf("filename cannot be empty")
+	}
+	if s.serializer == nil || s.serializer.MarshalFunc == nil {
+		return fmt.Errorf("no serializer configured for marshaling")
+	}
+
+	data, err := s.MarshalWith(s.serializer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal data: %w", err)
+	}
+	return writeFile(filename, data)
+}
+
+// tuples 返回按遍历顺序排列的键值对，写时复制模式下首次调用时从冻结的分片数据生成
+func (s *Snapshot[K, V]) tuples() []Tuple[K, V] {
+	if s.frozen != nil {
+		s.load.Do(func() {
+			s.items = s.frozen.collect(false)
+		})
+	}
+	return s.items
+}
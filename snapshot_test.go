@@ -0,0 +1,347 @@
+package cmap
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSnapshot 测试快照读取
+func TestSnapshot(t *testing.T) {
+	m := NewStringTreeMap[int]()
+	m.PutAll(map[string]int{"c": 3, "a": 1, "b": 2})
+
+	s := m.Snapshot()
+	m.Put("d", 4)
+	m.Remove("a")
+
+	if s.Size() != 3 || s.Empty() {
+		t.Errorf("Snapshot size got %d, want 3", s.Size())
+	}
+	if value, ok := s.Get("a"); !ok || value != 1 {
+		t.Errorf("Snapshot Get got (%v, %v), want (1, true)", value, ok)
+	}
+	if _, ok := s.Get("d"); ok {
+		t.Error("Snapshot should not see later writes")
+	}
+	keys := s.Keys()
+	if len(keys) != 3 || keys[0] != "a" || keys[1] != "b" || keys[2] != "c" {
+		t.Errorf("Snapshot keys got %v, want [a b c]", keys)
+	}
+	if values := s.Values(); len(values) != 3 || values[2] != 3 {
+		t.Errorf("Snapshot values got %v, want [1 2 3]", values)
+	}
+
+	// Items 返回副本
+	items := s.Items()
+	items[0].Value = 100
+	if value, _ := s.Get("a"); value != 1 {
+		t.Error("Modifying Items result should not affect snapshot")
+	}
+
+	count := 0
+	s.Range(func(key string, value int) bool {
+		count++
+		return true
+	})
+	if count != 3 {
+		t.Errorf("Snapshot Range visited %d items, want 3", count)
+	}
+}
+
+// TestSnapshotConsistency 测试快照是一致的时间切面
+func TestSnapshotConsistency(t *testing.T) {
+	m := NewStringHashMap[int](WithShardCount(16))
+
+	// 选取两个分片不同且 first 所在分片在前的键
+	var first, second string
+	for i := 0; second == ""; i++ {
+		key := "key" + strconv.Itoa(i)
+		switch {
+		case first == "":
+			first = key
+		case m.getShard(key) != m.getShard(first):
+			second = key
+			if m.hasher.Hash(first)&m.mask > m.hasher.Hash(second)&m.mask {
+				first, second = second, first
+			}
+		}
+	}
+
+	// 写入者先更新first再更新second，任何一致的时间切面都满足 second <= first
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; !stop.Load(); i++ {
+			m.Put(first, i)
+			m.Put(second, i)
+		}
+	}()
+
+	deadline := time.Now().Add(100 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		s := m.Snapshot()
+		a, _ := s.Get(first)
+		b, _ := s.Get(second)
+		if b > a {
+			t.Fatalf("Snapshot is not consistent: %s=%d, %s=%d", first, a, second, b)
+		}
+	}
+	stop.Store(true)
+	wg.Wait()
+}
+
+// TestSnapshotSaveToFile 测试从快照保存文件
+func TestSnapshotSaveToFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "snapshot.json")
+	m := NewStringHashMap[int]()
+	m.PutAll(map[string]int{"a": 1, "b": 2})
+
+	if err := m.Snapshot().SaveToFile(filename); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+
+	loaded := NewStringHashMap[int]()
+	if err := loaded.LoadFromFile(filename); err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if loaded.Size() != 2 {
+		t.Errorf("Loaded size %d, want 2", loaded.Size())
+	}
+}
+
+// TestClone 测试复制映射
+func TestClone(t *testing.T) {
+	clock := newFakeClock()
+	m := NewStringLinkedHashMap[int](WithInsertionOrder(), WithClock(clock.Now))
+	m.Put("c", 3)
+	m.Put("a", 1)
+	m.PutWithTTL("b", 2, time.Minute)
+
+	c := m.Clone()
+	defer c.Close()
+	m.Put("a", 100)
+	c.Put("d", 4)
+
+	if value, _ := c.Get("a"); value != 1 {
+		t.Errorf("Clone should be independent, got a=%v, want 1", value)
+	}
+	if _, ok := m.Get("d"); ok {
+		t.Error("Writes to clone should not affect source")
+	}
+	if keys := c.Keys(); len(keys) != 4 || keys[0] != "c" || keys[1] != "a" || keys[2] != "b" || keys[3] != "d" {
+		t.Errorf("Clone should keep insertion order, got %v", keys)
+	}
+	if ttl, ok := c.TTL("b"); !ok || ttl != time.Minute {
+		t.Errorf("Clone should keep ttl, got (%v, %v)", ttl, ok)
+	}
+	if fmt.Sprintf("%T", c.shards[0].m) != fmt.Sprintf("%T", m.shards[0].m) {
+		t.Error("Clone should use the same backend type")
+	}
+	if !c.IsDirty() {
+		t.Error("Clone should keep dirty state")
+	}
+
+	clock.Advance(time.Minute)
+	if _, ok := c.Get("b"); ok {
+		t.Error("Cloned ttl should expire")
+	}
+}
+
+// TestCloneJanitor 测试复制时与副本的后台清理协程并发，需配合 -race 运行
+func TestCloneJanitor(t *testing.T) {
+	m := New[int, int](WithJanitor(time.Microsecond), WithShardCount(1))
+	defer m.Close()
+	for i := 0; i < 20000; i++ {
+		m.PutWithTTL(i, i, time.Hour)
+	}
+
+	c := m.Clone()
+	defer c.Close()
+	if size := c.Size(); size != 20000 {
+		t.Errorf("Clone size got %d, want 20000", size)
+	}
+}
+
+// TestCloneWithoutStore 测试副本不写入原映射的Store
+func TestCloneWithoutStore(t *testing.T) {
+	for _, writeBehind := range []bool{false, true} {
+		store := NewMemoryStore[string, int]()
+		options := []Option{WithStore[string, int](store)}
+		if writeBehind {
+			options = append(options, WithWriteBehind(time.Millisecond, 0))
+		}
+		m := NewStringHashMap[int](options...)
+		m.Put("a", 1)
+		if err := m.Flush(context.Background()); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+
+		c := m.Clone()
+		c.Put("a", 2)
+		c.Put("b", 3)
+		c.Remove("a")
+		if err := c.Flush(context.Background()); err != nil {
+			t.Fatalf("Clone Flush failed: %v", err)
+		}
+		c.Close()
+
+		if value, found, _ := store.Load(context.Background(), "a"); !found || value != 1 {
+			t.Errorf("Clone should not write original store, got a=(%v, %v)", value, found)
+		}
+		if store.Len() != 1 {
+			t.Errorf("Clone should not write original store, store size %d, want 1", store.Len())
+		}
+		m.Close()
+	}
+}
+
+// TestSnapshotCopyOnWrite 测试写时复制快照
+func TestSnapshotCopyOnWrite(t *testing.T) {
+	constructors := map[string]func() *Map[string, int]{
+		"HashMap":       func() *Map[string, int] { return NewStringHashMap[int](WithCopyOnWrite(), WithShardCount(4)) },
+		"TreeMap":       func() *Map[string, int] { return NewStringTreeMap[int](WithCopyOnWrite(), WithShardCount(4)) },
+		"LinkedHashMap": func() *Map[string, int] { return NewStringLinkedHashMap[int](WithCopyOnWrite(), WithInsertionOrder()) },
+	}
+
+	for name, create := range constructors {
+		t.Run(name, func(t *testing.T) {
+			m := create()
+			for i := 0; i < 100; i++ {
+				m.Put("key"+strconv.Itoa(i), i)
+			}
+			before := m.Keys()
+
+			s := m.Snapshot()
+			defer s.Release()
+			for i := 0; i < 50; i++ {
+				m.Put("key"+strconv.Itoa(i), -i)
+				m.Remove("key" + strconv.Itoa(i+50))
+			}
+			m.Put("new", 1)
+			m.Clear()
+			m.Put("after", 1)
+
+			if s.Size() != 100 {
+				t.Errorf("Snapshot size got %d, want 100", s.Size())
+			}
+			for i := 0; i < 100; i++ {
+				if value, ok := s.Get("key" + strconv.Itoa(i)); !ok || value != i {
+					t.Fatalf("Snapshot Get key%d got (%v, %v), want (%d, true)", i, value, ok, i)
+				}
+			}
+			if _, ok := s.Get("new"); ok {
+				t.Error("Snapshot should not see later writes")
+			}
+			keys := s.Keys()
+			if name == "HashMap" {
+				// HashMap 的遍历顺序不固定
+				sort.Strings(keys)
+				sort.Strings(before)
+			}
+			if len(keys) != len(before) {
+				t.Fatalf("Snapshot keys length got %d, want %d", len(keys), len(before))
+			}
+			for i := range keys {
+				if keys[i] != before[i] {
+					t.Fatalf("Snapshot keys order differs at %d: %s != %s", i, keys[i], before[i])
+				}
+			}
+			if m.Size() != 1 {
+				t.Errorf("Map size got %d, want 1", m.Size())
+			}
+		})
+	}
+}
+
+// TestSnapshotCopyOnWriteLazyCopy 测试只有被快照引用的分片在写入时才复制
+func TestSnapshotCopyOnWriteLazyCopy(t *testing.T) {
+	m := NewStringHashMap[int](WithCopyOnWrite(), WithShardCount(1))
+	m.Put("a", 1)
+
+	backing := m.shards[0].m
+	m.Put("b", 2)
+	if m.shards[0].m != backing {
+		t.Error("Writes without live snapshot should not copy shard")
+	}
+
+	s := m.Snapshot()
+	m.Put("c", 3)
+	copied := m.shards[0].m
+	if copied == backing {
+		t.Error("First write with live snapshot should copy shard")
+	}
+	m.Put("d", 4)
+	if m.shards[0].m != copied {
+		t.Error("Shard should be copied only once per snapshot")
+	}
+
+	s.Release()
+	s.Release()
+	s = m.Snapshot()
+	s.Release()
+	m.Put("e", 5)
+	if m.shards[0].m != copied {
+		t.Error("Released snapshot should not cause copy")
+	}
+}
+
+// TestSnapshotCopyOnWriteExpire 测试写时复制快照按快照时刻判断过期
+func TestSnapshotCopyOnWriteExpire(t *testing.T) {
+	clock := newFakeClock()
+	m := NewStringHashMap[int](WithCopyOnWrite(), WithClock(clock.Now))
+	m.PutWithTTL("a", 1, time.Second)
+
+	s := m.Snapshot()
+	defer s.Release()
+	clock.Advance(time.Second)
+
+	if _, ok := m.Get("a"); ok {
+		t.Error("Key should expire in map")
+	}
+	if value, ok := s.Get("a"); !ok || value != 1 {
+		t.Errorf("Snapshot should keep key alive at snapshot time, got (%v, %v)", value, ok)
+	}
+	if s.Size() != 1 || len(s.Keys()) != 1 {
+		t.Error("Snapshot should contain key alive at snapshot time")
+	}
+}
+
+// TestSnapshotCopyOnWriteConcurrent 测试写时复制快照与并发写入
+func TestSnapshotCopyOnWriteConcurrent(t *testing.T) {
+	m := NewIntHashMap[int](WithCopyOnWrite(), WithShardCount(8))
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; !stop.Load(); i++ {
+				m.Put(i%100, w)
+				m.Remove((i + 50) % 100)
+			}
+		}(w)
+	}
+
+	for i := 0; i < 50; i++ {
+		s := m.Snapshot()
+		size := s.Size()
+		if len(s.Keys()) != size {
+			t.Errorf("Snapshot keys length %d differs from size %d", len(s.Keys()), size)
+		}
+		if _, err := s.MarshalWith(JsonSerializer()); err != nil {
+			t.Errorf("MarshalWith failed: %v", err)
+		}
+		s.Release()
+	}
+	stop.Store(true)
+	wg.Wait()
+}
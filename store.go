@@ -0,0 +1,269 @@
+package cmap
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNotFound Store 中不存在该键
+var ErrNotFound = errors.New("cmap: key not found")
+
+// Store 映射的后端存储
+//
+// 配置 WithStore 后，Put、Remove 等写操作会同步写入（write-through）或异步批量写入（write-behind）Store。
+// 过期、淘汰和 Clear 只影响内存中的数据，不会删除 Store 中的键。
+type Store[K cmp.Ordered, V any] interface {
+	// Load 读取键，不存在时found为false
+	Load(ctx context.Context, key K) (value V, found bool, err error)
+	// Save 写入键值对
+	Save(ctx context.Context, key K, value V) error
+	// Delete 删除键，键不存在时不返回错误
+	Delete(ctx context.Context, key K) error
+	// LoadBatch 批量读取，返回的结果中只包含存在的键
+	LoadBatch(ctx context.Context, keys []K) (map[K]V, error)
+	// SaveBatch 批量写入
+	SaveBatch(ctx context.Context, items map[K]V) error
+	// DeleteBatch 批量删除
+	DeleteBatch(ctx context.Context, keys []K) error
+}
+
+// Flush 将 write-behind 模式下尚未写入的变更立即写入 Store
+//
+// 写入失败的变更会重新排队，等待下次写入。未开启 write-behind 时直接返回nil。
+func (m *Map[K, V]) Flush(ctx context.Context) error {
+	if m.writer == nil {
+		return nil
+	}
+	return m.writer.flush(ctx)
+}
+
+// LoadFromStore 从 Store 批量读取键并写入映射，Store 中不存在的键会被忽略
+//
+// 读取的值按默认TTL写入映射，不会再写回 Store。被 WithBeforePut 拒绝的值不会写入，其错误合并后返回。
+func (m *Map[K, V]) LoadFromStore(ctx context.Context, keys ...K) error {
+	if m.writer == nil {
+		return fmt.Errorf("cmap: no store configured")
+	}
+
+	items, err := m.writer.store.LoadBatch(ctx, keys)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for key, value := range items {
+		if err = m.fill(key, value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadFromStore 作为 GetOrLoad 的默认加载函数，从 Store 读取键
+func (m *Map[K, V]) loadFromStore(ctx context.Context, key K) (V, error) {
+	value, found, err := m.writer.store.Load(ctx, key)
+	if err == nil && !found {
+		err = ErrNotFound
+	}
+	return value, err
+}
+
+// fill 写入从外部加载的值，不会写回 Store，WithBeforePut 拒绝写入时返回其错误
+func (m *Map[K, V]) fill(key K, value V) error {
+	if err := m.check(key, value); err != nil {
+		return err
+	}
+	m.restore(key, value)
+	return nil
+}
+
+// restore 写入已校验过的外部值，按默认TTL过期，触发 OnPut 回调和订阅事件，但不会写回 Store
+func (m *Map[K, V]) restore(key K, value V) {
+	sh := m.getShard(key)
+	sh.mu.Lock()
+	old, replaced := sh.set(key, value, sh.opts.DefaultTTL)
+	sh.recordWrite(change[K, V]{kind: changeLoad, key: key, old: old, value: value, replaced: replaced})
+	m.unlock(sh)
+}
+
+// startFlusher 启动 write-behind 后台写入协程
+func (m *Map[K, V]) startFlusher(interval time.Duration) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-tick:
+			case <-m.writer.wake:
+			}
+			if err := m.writer.flush(context.Background()); err != nil {
+				m.writer.fail(err)
+			}
+		}
+	}()
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// storeOp 等待写入 Store 的变更，同一个键的多次变更只保留最后一次
+type storeOp[V any] struct {
+	value  V
+	remove bool
+}
+
+// storeWriter 负责将映射的变更写入 Store
+type storeWriter[K cmp.Ordered, V any] struct {
+	store   Store[K, V]
+	behind  bool        // 是否为 write-behind 模式
+	batch   int         // 每批写入的最大键数量，0表示不限制
+	onError func(error) // 写入失败回调
+
+	mu      sync.Mutex
+	pending map[K]storeOp[V] // 尚未写入的变更
+	wake    chan struct{}    // 待写入的变更达到batch时唤醒后台协程
+	flushMu sync.Mutex       // 保证同时只有一次批量写入
+}
+
+// newStoreWriter 创建写入器
+func newStoreWriter[K cmp.Ordered, V any](store Store[K, V], opts *Options) *storeWriter[K, V] {
+	w := &storeWriter[K, V]{
+		store:   store,
+		behind:  opts.WriteBehind,
+		batch:   opts.WriteBehindBatch,
+		onError: opts.OnStoreError,
+	}
+	if w.behind {
+		w.pending = make(map[K]storeOp[V])
+		w.wake = make(chan struct{}, 1)
+	}
+	return w
+}
+
+// write 写入分片锁内产生的变更，调用方需持有分片写锁，以保证同一个键的变更按顺序写入
+func (w *storeWriter[K, V]) write(changes []change[K, V]) {
+	for _, c := range changes {
+		if c.kind != changePut && c.kind != changeRemove {
+			continue
+		}
+		if w.behind {
+			w.enqueue(c.key, storeOp[V]{value: c.value, remove: c.kind == changeRemove})
+			continue
+		}
+
+		var err error
+		if c.kind == changePut {
+			err = w.store.Save(context.Background(), c.key, c.value)
+		} else {
+			err = w.store.Delete(context.Background(), c.key)
+		}
+		if err != nil {
+			w.fail(fmt.Errorf("cmap: store key %v: %w", c.key, err))
+		}
+	}
+}
+
+// enqueue 加入待写入的变更，覆盖同一个键之前未写入的变更
+func (w *storeWriter[K, V]) enqueue(key K, op storeOp[V]) {
+	w.mu.Lock()
+	w.pending[key] = op
+	full := w.batch > 0 && len(w.pending) >= w.batch
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// requeue 将写入失败的变更重新排队，期间已有更新变更的键保持不变
+func (w *storeWriter[K, V]) requeue(ops map[K]storeOp[V]) {
+	w.mu.Lock()
+	for key, op := range ops {
+		if _, ok := w.pending[key]; !ok {
+			w.pending[key] = op
+		}
+	}
+	w.mu.Unlock()
+}
+
+// flush 分批写入所有待写入的变更
+func (w *storeWriter[K, V]) flush(ctx context.Context) error {
+	if !w.behind {
+		return nil
+	}
+
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	ops := w.pending
+	w.pending = make(map[K]storeOp[V])
+	w.mu.Unlock()
+
+	var errs []error
+	for len(ops) > 0 {
+		if err := ctx.Err(); err != nil {
+			w.requeue(ops)
+			return err
+		}
+
+		chunk := make(map[K]storeOp[V])
+		for key, op := range ops {
+			if w.batch > 0 && len(chunk) >= w.batch {
+				break
+			}
+			chunk[key] = op
+			delete(ops, key)
+		}
+		if err := w.flushChunk(ctx, chunk); err != nil {
+			w.requeue(chunk)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flushChunk 写入一批变更
+func (w *storeWriter[K, V]) flushChunk(ctx context.Context, chunk map[K]storeOp[V]) error {
+	saves := make(map[K]V)
+	var removes []K
+	for key, op := range chunk {
+		if op.remove {
+			removes = append(removes, key)
+		} else {
+			saves[key] = op.value
+		}
+	}
+
+	if len(saves) > 0 {
+		if err := w.store.SaveBatch(ctx, saves); err != nil {
+			return fmt.Errorf("cmap: store save batch: %w", err)
+		}
+	}
+	if len(removes) > 0 {
+		if err := w.store.DeleteBatch(ctx, removes); err != nil {
+			return fmt.Errorf("cmap: store delete batch: %w", err)
+		}
+	}
+	return nil
+}
+
+// fail 报告写入错误
+func (w *storeWriter[K, V]) fail(err error) {
+	if w.onError != nil {
+		w.onError(err)
+	}
+}
@@ -0,0 +1,242 @@
+package cmap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingStore 记录批量写入次数的存储，可注入写入错误
+type countingStore struct {
+	*MemoryStore[string, int]
+	mu      sync.Mutex
+	saves   int
+	batches []int
+	err     error
+}
+
+func newCountingStore() *countingStore {
+	return &countingStore{MemoryStore: NewMemoryStore[string, int]()}
+}
+
+func (s *countingStore) Save(ctx context.Context, key string, value int) error {
+	s.mu.Lock()
+	s.saves++
+	err := s.err
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.MemoryStore.Save(ctx, key, value)
+}
+
+func (s *countingStore) SaveBatch(ctx context.Context, items map[string]int) error {
+	s.mu.Lock()
+	s.batches = append(s.batches, len(items))
+	err := s.err
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.MemoryStore.SaveBatch(ctx, items)
+}
+
+func (s *countingStore) setErr(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// TestWriteThrough 测试同步写入
+func TestWriteThrough(t *testing.T) {
+	store := NewMemoryStore[string, int]()
+	m := NewStringHashMap[int](WithStore[string, int](store))
+
+	m.Put("a", 1)
+	m.Put("b", 2)
+	m.Compute("c", func(int, bool) (int, bool) { return 3, true })
+	m.Remove("b")
+
+	if value, found, _ := store.Load(context.Background(), "a"); !found || value != 1 {
+		t.Errorf("Store should contain a=1, got (%v, %v)", value, found)
+	}
+	if value, found, _ := store.Load(context.Background(), "c"); !found || value != 3 {
+		t.Errorf("Store should contain c=3, got (%v, %v)", value, found)
+	}
+	if _, found, _ := store.Load(context.Background(), "b"); found {
+		t.Error("Removed key should be deleted from store")
+	}
+
+	// 清空只影响内存
+	m.Clear()
+	if store.Len() != 2 {
+		t.Errorf("Clear should not touch store, store size %d, want 2", store.Len())
+	}
+}
+
+// TestWriteThroughError 测试同步写入失败
+func TestWriteThroughError(t *testing.T) {
+	store := newCountingStore()
+	errBroken := errors.New("broken")
+	store.setErr(errBroken)
+
+	var reported error
+	m := NewStringHashMap[int](WithStore[string, int](store), WithOnStoreError(func(err error) {
+		reported = err
+	}))
+	m.Put("a", 1)
+
+	if !errors.Is(reported, errBroken) {
+		t.Errorf("OnStoreError got %v, want %v", reported, errBroken)
+	}
+	if value, _ := m.Get("a"); value != 1 {
+		t.Error("Failed store write should keep value in memory")
+	}
+}
+
+// TestWriteBehind 测试异步批量写入与合并
+func TestWriteBehind(t *testing.T) {
+	store := newCountingStore()
+	m := NewStringHashMap[int](WithStore[string, int](store), WithWriteBehind(0, 0))
+	defer m.Close()
+
+	for i := 0; i < 100; i++ {
+		m.Put("a", i)
+	}
+	m.Put("b", 1)
+	m.Put("c", 1)
+	m.Remove("c")
+
+	if store.Len() != 0 {
+		t.Error("Write-behind should not write before flush")
+	}
+	if err := m.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if value, _, _ := store.Load(context.Background(), "a"); value != 99 {
+		t.Errorf("Store should contain latest value 99, got %v", value)
+	}
+	if _, found, _ := store.Load(context.Background(), "c"); found {
+		t.Error("Removed key should not be saved")
+	}
+	if len(store.batches) != 1 || store.batches[0] != 2 {
+		t.Errorf("Writes should be coalesced into one batch of 2, got %v", store.batches)
+	}
+	if store.saves != 0 {
+		t.Errorf("Write-behind should only use batch writes, got %d single saves", store.saves)
+	}
+}
+
+// TestWriteBehindBatch 测试达到批量大小时自动写入
+func TestWriteBehindBatch(t *testing.T) {
+	s := NewMemoryStore[int, int]()
+	m := NewIntHashMap[int](WithStore[int, int](s), WithWriteBehind(time.Hour, 10))
+	defer m.Close()
+	for i := 0; i < 10; i++ {
+		m.Put(i, i)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for s.Len() < 10 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if s.Len() != 10 {
+		t.Errorf("Reaching batch size should trigger flush, store size %d, want 10", s.Len())
+	}
+}
+
+// TestWriteBehindRetry 测试写入失败后重新排队
+func TestWriteBehindRetry(t *testing.T) {
+	store := newCountingStore()
+	m := NewStringHashMap[int](WithStore[string, int](store), WithWriteBehind(0, 0))
+
+	errBroken := errors.New("broken")
+	store.setErr(errBroken)
+	m.Put("a", 1)
+	if err := m.Flush(context.Background()); !errors.Is(err, errBroken) {
+		t.Errorf("Flush error got %v, want %v", err, errBroken)
+	}
+
+	// 失败期间的新写入优先于重新排队的旧变更
+	m.Put("a", 2)
+	store.setErr(nil)
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if value, _, _ := store.Load(context.Background(), "a"); value != 2 {
+		t.Errorf("Store should contain 2 after retry, got %v", value)
+	}
+}
+
+// TestWriteBehindFlushCanceled 测试Flush时ctx已取消
+func TestWriteBehindFlushCanceled(t *testing.T) {
+	store := NewMemoryStore[string, int]()
+	m := NewStringHashMap[int](WithStore[string, int](store), WithWriteBehind(0, 0))
+	m.Put("a", 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.Flush(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Flush error got %v, want %v", err, context.Canceled)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if store.Len() != 1 {
+		t.Error("Canceled changes should be written on Close")
+	}
+}
+
+// TestStoreLoad 测试从Store读取
+func TestStoreLoad(t *testing.T) {
+	store := newCountingStore()
+	_ = store.MemoryStore.SaveBatch(context.Background(), map[string]int{"a": 1, "b": 2})
+	m := NewStringHashMap[int](WithStore[string, int](store))
+
+	value, err := m.GetOrLoad(context.Background(), "a", nil)
+	if err != nil || value != 1 {
+		t.Errorf("GetOrLoad from store got (%v, %v), want (1, nil)", value, err)
+	}
+	if _, err = m.GetOrLoad(context.Background(), "missing", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetOrLoad missing key error got %v, want %v", err, ErrNotFound)
+	}
+
+	if err = m.LoadFromStore(context.Background(), "b", "missing"); err != nil {
+		t.Fatalf("LoadFromStore failed: %v", err)
+	}
+	if value, _ := m.Get("b"); value != 2 {
+		t.Errorf("LoadFromStore should put b=2, got %v", value)
+	}
+	if store.saves != 0 {
+		t.Errorf("Loaded values should not be written back, got %d saves", store.saves)
+	}
+}
+
+// TestUnmarshalDoesNotWriteStore 测试从文件加载的数据不写回 Store
+func TestUnmarshalDoesNotWriteStore(t *testing.T) {
+	store := newCountingStore()
+	var puts []string
+	m := NewStringHashMap[int](
+		WithStore[string, int](store),
+		WithOnPut(func(key string, oldValue, newValue int, replaced bool) { puts = append(puts, key) }),
+	)
+
+	if err := m.UnmarshalJSON([]byte(`{"items":[{"key":"a","value":1},{"key":"b","value":2}]}`)); err != nil {
+		t.Fatalf("UnmarshalJSON failed: %v", err)
+	}
+	if value, _ := m.Get("b"); value != 2 {
+		t.Errorf("Loaded b got %d, want 2", value)
+	}
+	if store.saves != 0 || store.Len() != 0 {
+		t.Errorf("Loaded data should not be saved to store, saves %d, size %d", store.saves, store.Len())
+	}
+	if len(puts) != 2 {
+		t.Errorf("OnPut should fire for loaded keys, got %v", puts)
+	}
+	if m.IsDirty() {
+		t.Error("Loaded map should not be dirty")
+	}
+}
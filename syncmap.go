@@ -0,0 +1,110 @@
+package cmap
+
+// 本文件提供与 sync.Map 语义一致的原子操作，便于从 sync.Map 平滑迁移
+
+// PutIfAbsent 键不存在时插入值
+//
+// 键已存在时返回已有值且 loaded 为 true；否则插入 value 并返回 value，loaded 为 false。
+// 键不存在且 WithBeforePut 拒绝写入时返回零值，loaded 为 false。
+func (m *Map[K, V]) PutIfAbsent(key K, value V) (actual V, loaded bool) {
+	rejected := m.check(key, value) != nil
+
+	sh := m.getShard(key)
+	sh.mu.Lock()
+	actual, loaded = sh.get(key)
+	if !loaded && !rejected {
+		sh.put(key, value)
+		actual = value
+	}
+	m.unlock(sh)
+	return
+}
+
+// GetOrPut 获取键对应的值，不存在时插入value，等同于 PutIfAbsent
+func (m *Map[K, V]) GetOrPut(key K, value V) (actual V, loaded bool) {
+	return m.PutIfAbsent(key, value)
+}
+
+// LoadOrStore 等同于 sync.Map.LoadOrStore
+func (m *Map[K, V]) LoadOrStore(key K, value V) (actual V, loaded bool) {
+	return m.PutIfAbsent(key, value)
+}
+
+// LoadAndDelete 删除键并返回被删除的值，loaded 表示键是否存在
+func (m *Map[K, V]) LoadAndDelete(key K) (value V, loaded bool) {
+	sh := m.getShard(key)
+	sh.mu.Lock()
+	value, loaded = sh.remove(key)
+	m.unlock(sh)
+	return
+}
+
+// Swap 写入新值并返回旧值，loaded 表示键原先是否存在
+//
+// WithBeforePut 拒绝写入时不修改映射，返回当前值。
+func (m *Map[K, V]) Swap(key K, value V) (previous V, loaded bool) {
+	if m.check(key, value) != nil {
+		return m.Get(key)
+	}
+
+	sh := m.getShard(key)
+	sh.mu.Lock()
+	previous, loaded = sh.put(key, value)
+	m.unlock(sh)
+	return
+}
+
+// CompareAndSwap 当前值与old相等时写入new，返回是否写入成功
+//
+// 比较与写入在同一把分片锁内完成。值的比较方式见 WithEqual。
+func (m *Map[K, V]) CompareAndSwap(key K, old, new V) (swapped bool) {
+	if m.check(key, new) != nil {
+		return false
+	}
+
+	sh := m.getShard(key)
+	sh.mu.Lock()
+	defer m.unlock(sh) // 值不可比较时 equal 会 panic，同样需要释放分片锁
+	current, found := sh.get(key)
+	if found && m.equal(current, old) {
+		sh.put(key, new)
+		swapped = true
+	}
+	return
+}
+
+// CompareAndDelete 当前值与old相等时删除该键，返回是否删除成功
+func (m *Map[K, V]) CompareAndDelete(key K, old V) (deleted bool) {
+	sh := m.getShard(key)
+	sh.mu.Lock()
+	defer m.unlock(sh)
+	current, found := sh.get(key)
+	if found && m.equal(current, old) {
+		sh.remove(key)
+		deleted = true
+	}
+	return
+}
+
+// equality 从Options中取出值比较函数，未设置时使用 == 比较
+func equality[V any](v any) func(a, b V) bool {
+	if fn := hook[func(V, V) bool](v); fn != nil {
+		return fn
+	}
+	return func(a, b V) bool { return any(a) == any(b) }
+}
+
+// Load 等同于 Get，对应 sync.Map.Load
+func (m *Map[K, V]) Load(key K) (value V, ok bool) {
+	return m.Get(key)
+}
+
+// Store 等同于 Put，对应 sync.Map.Store
+func (m *Map[K, V]) Store(key K, value V) {
+	m.Put(key, value)
+}
+
+// Delete 等同于 Remove，对应 sync.Map.Delete
+func (m *Map[K, V]) Delete(key K) {
+	m.Remove(key)
+}
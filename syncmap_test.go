@@ -0,0 +1,235 @@
+package cmap
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestPutIfAbsent 测试PutIfAbsent
+func TestPutIfAbsent(t *testing.T) {
+	m := NewStringHashMap[int]()
+
+	actual, loaded := m.PutIfAbsent("key", 1)
+	if loaded || actual != 1 {
+		t.Errorf("PutIfAbsent on missing key got (%v, %v), want (1, false)", actual, loaded)
+	}
+
+	actual, loaded = m.PutIfAbsent("key", 2)
+	if !loaded || actual != 1 {
+		t.Errorf("PutIfAbsent on existing key got (%v, %v), want (1, true)", actual, loaded)
+	}
+
+	actual, loaded = m.GetOrPut("key", 3)
+	if !loaded || actual != 1 {
+		t.Errorf("GetOrPut on existing key got (%v, %v), want (1, true)", actual, loaded)
+	}
+
+	actual, loaded = m.LoadOrStore("other", 4)
+	if loaded || actual != 4 {
+		t.Errorf("LoadOrStore on missing key got (%v, %v), want (4, false)", actual, loaded)
+	}
+}
+
+// TestLoadAndDelete 测试LoadAndDelete
+func TestLoadAndDelete(t *testing.T) {
+	m := NewStringHashMap[int]()
+	m.Put("key", 42)
+
+	value, loaded := m.LoadAndDelete("key")
+	if !loaded || value != 42 {
+		t.Errorf("LoadAndDelete got (%v, %v), want (42, true)", value, loaded)
+	}
+	if _, ok := m.Get("key"); ok {
+		t.Error("LoadAndDelete should remove the key")
+	}
+
+	value, loaded = m.LoadAndDelete("key")
+	if loaded || value != 0 {
+		t.Errorf("LoadAndDelete on missing key got (%v, %v), want (0, false)", value, loaded)
+	}
+}
+
+// TestSwap 测试Swap
+func TestSwap(t *testing.T) {
+	m := NewStringHashMap[int]()
+
+	previous, loaded := m.Swap("key", 1)
+	if loaded || previous != 0 {
+		t.Errorf("Swap on missing key got (%v, %v), want (0, false)", previous, loaded)
+	}
+
+	previous, loaded = m.Swap("key", 2)
+	if !loaded || previous != 1 {
+		t.Errorf("Swap on existing key got (%v, %v), want (1, true)", previous, loaded)
+	}
+
+	if value, _ := m.Get("key"); value != 2 {
+		t.Errorf("Swap should store new value, got %v, want 2", value)
+	}
+}
+
+// TestSyncMapAliases 测试sync.Map风格的别名方法
+func TestSyncMapAliases(t *testing.T) {
+	m := NewStringHashMap[int]()
+
+	m.Store("key", 1)
+	if value, ok := m.Load("key"); !ok || value != 1 {
+		t.Errorf("Store/Load failed, got (%v, %v), want (1, true)", value, ok)
+	}
+
+	m.Delete("key")
+	if _, ok := m.Load("key"); ok {
+		t.Error("Delete failed, key still exists")
+	}
+}
+
+// TestPutIfAbsentConcurrent 测试PutIfAbsent的并发原子性
+func TestPutIfAbsentConcurrent(t *testing.T) {
+	m := NewStringHashMap[int]()
+	const goroutines = 100
+
+	var wg sync.WaitGroup
+	var stored int32
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			if _, loaded := m.PutIfAbsent("key", id); !loaded {
+				atomic.AddInt32(&stored, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if stored != 1 {
+		t.Errorf("PutIfAbsent should store exactly once, stored %d times", stored)
+	}
+}
+
+// TestCompareAndSwap 测试CompareAndSwap
+func TestCompareAndSwap(t *testing.T) {
+	m := NewStringHashMap[string]()
+
+	if m.CompareAndSwap("job", "pending", "running") {
+		t.Error("CompareAndSwap should fail for missing key")
+	}
+
+	m.Put("job", "pending")
+	if !m.CompareAndSwap("job", "pending", "running") {
+		t.Error("CompareAndSwap should succeed when value matches")
+	}
+	if m.CompareAndSwap("job", "pending", "done") {
+		t.Error("CompareAndSwap should fail when value does not match")
+	}
+	if value, _ := m.Get("job"); value != "running" {
+		t.Errorf("CompareAndSwap stored wrong value, got %v, want running", value)
+	}
+}
+
+// TestCompareAndDelete 测试CompareAndDelete
+func TestCompareAndDelete(t *testing.T) {
+	m := NewStringHashMap[int]()
+	m.Put("key", 1)
+
+	if m.CompareAndDelete("key", 2) {
+		t.Error("CompareAndDelete should fail when value does not match")
+	}
+	if !m.CompareAndDelete("key", 1) {
+		t.Error("CompareAndDelete should succeed when value matches")
+	}
+	if _, ok := m.Get("key"); ok {
+		t.Error("CompareAndDelete should remove the key")
+	}
+	if m.CompareAndDelete("key", 1) {
+		t.Error("CompareAndDelete should fail for missing key")
+	}
+}
+
+// TestCompareAndSwapWithEqual 测试自定义比较函数
+func TestCompareAndSwapWithEqual(t *testing.T) {
+	m := NewStringHashMap[[]int](WithEqual(func(a, b []int) bool {
+		return len(a) == len(b)
+	}))
+	m.Put("key", []int{1, 2})
+
+	if !m.CompareAndSwap("key", []int{3, 4}, []int{5}) {
+		t.Error("CompareAndSwap should use custom equal function")
+	}
+	if !m.CompareAndDelete("key", []int{0}) {
+		t.Error("CompareAndDelete should use custom equal function")
+	}
+}
+
+// TestCompareAndSwapConcurrent 测试CompareAndSwap的并发原子性
+func TestCompareAndSwapConcurrent(t *testing.T) {
+	m := NewStringHashMap[int]()
+	m.Put("counter", 0)
+	const goroutines = 50
+	const operations = 100
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < operations; j++ {
+				for {
+					old, _ := m.Get("counter")
+					if m.CompareAndSwap("counter", old, old+1) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if value, _ := m.Get("counter"); value != goroutines*operations {
+		t.Errorf("Concurrent CompareAndSwap lost updates, got %d, want %d", value, goroutines*operations)
+	}
+}
+
+// TestCompareAndSwapNotComparable 测试值不可比较时 panic 后映射仍可使用
+func TestCompareAndSwapNotComparable(t *testing.T) {
+	m := NewStringHashMap[[]int](WithShardCount(1))
+	m.Put("key", []int{1})
+
+	calls := []func(){
+		func() { m.CompareAndSwap("key", []int{1}, []int{2}) },
+		func() { m.CompareAndDelete("key", []int{1}) },
+	}
+	for i, call := range calls {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Call %d: comparing slices should panic", i)
+				}
+			}()
+			call()
+		}()
+
+		done := make(chan struct{})
+		go func() {
+			m.Put("other", []int{i})
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Call %d: shard lock should be released after panic", i)
+		}
+	}
+
+	// 设置 WithEqual 后可以比较切片
+	m = NewStringHashMap[[]int](WithEqual(slices.Equal[[]int]))
+	m.Put("key", []int{1})
+	if !m.CompareAndSwap("key", []int{1}, []int{2}) {
+		t.Error("CompareAndSwap with WithEqual should succeed")
+	}
+	if !m.CompareAndDelete("key", []int{2}) {
+		t.Error("CompareAndDelete with WithEqual should succeed")
+	}
+}
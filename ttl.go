@@ -0,0 +1,154 @@
+package cmap
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// PutWithTTL 插入键值对并指定存活时间，ttl<=0表示永不过期
+//
+// 过期的键对 Get、Size、Keys、遍历和序列化均不可见。之后通过 Put 等方法再次写入该键时，
+// 存活时间会按默认TTL（见 WithDefaultTTL）重新设置。
+func (m *Map[K, V]) PutWithTTL(key K, value V, ttl time.Duration) {
+	if m.check(key, value) != nil {
+		return
+	}
+
+	sh := m.getShard(key)
+	sh.mu.Lock()
+	sh.putTTL(key, value, ttl)
+	m.unlock(sh)
+}
+
+// TTL 返回键的剩余存活时间，键不存在或已过期时ok为false，永不过期的键返回0
+func (m *Map[K, V]) TTL(key K) (ttl time.Duration, ok bool) {
+	sh := m.getShard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+
+	if _, ok = sh.get(key); !ok {
+		return 0, false
+	}
+	if e, has := sh.expires[key]; has {
+		ttl = time.Duration(e.deadline - sh.now())
+	}
+	return ttl, true
+}
+
+// Close 停止后台清理和写入协程，并将 write-behind 模式下剩余的变更写入 Store，可重复调用
+func (m *Map[K, V]) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.stop)
+	})
+	m.wg.Wait()
+	return m.Flush(context.Background())
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// expire 在写锁内删除已过期的键
+func (m *Map[K, V]) expire(sh *shard[K, V], key K) {
+	sh.mu.Lock()
+	// 获取写锁期间键可能已被更新，需要重新检查
+	value, found := sh.find(key)
+	expired := found && sh.expired(key, sh.now())
+	if expired {
+		sh.dropExpired(key, value)
+	}
+	m.unlock(sh)
+}
+
+// startJanitor 启动后台清理协程，每次只锁定一个分片
+func (m *Map[K, V]) startJanitor(interval time.Duration) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				m.sweep()
+			}
+		}
+	}()
+}
+
+// sweep 逐个分片删除已过期的键，返回删除的数量
+func (m *Map[K, V]) sweep() int {
+	removed := 0
+	for i := range m.shards {
+		sh := &m.shards[i]
+		sh.mu.Lock()
+		removed += sh.sweep()
+		m.unlock(sh)
+	}
+	return removed
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// expiry 键的过期信息
+type expiry struct {
+	deadline int64         // 过期时间（UnixNano）
+	ttl      time.Duration // 存活时间，滑动过期时用于延长deadline
+}
+
+// now 返回当前时间（UnixNano），设置了 WithClock 时使用自定义时钟
+func (o *Options) now() int64 {
+	if o.Clock != nil {
+		return o.Clock().UnixNano()
+	}
+	return time.Now().UnixNano()
+}
+
+// now 返回当前时间（UnixNano）
+func (sh *shard[K, V]) now() int64 {
+	return sh.opts.now()
+}
+
+// expired 检查键是否已过期，调用方需持有分片锁
+func (sh *shard[K, V]) expired(key K, now int64) bool {
+	e, ok := sh.expires[key]
+	return ok && e.deadline <= now
+}
+
+// slide 按键的TTL延长其存活时间，调用方需持有分片写锁
+func (sh *shard[K, V]) slide(key K, now int64) {
+	if e, ok := sh.expires[key]; ok {
+		sh.own()
+		e.deadline = now + int64(e.ttl)
+		sh.expires[key] = e
+	}
+}
+
+// dropExpired 删除已过期的键并记录过期变更，调用方需持有分片写锁
+func (sh *shard[K, V]) dropExpired(key K, value V) {
+	sh.drop(key)
+	sh.record(change[K, V]{kind: changeExpire, key: key, old: value, replaced: true})
+}
+
+// sweep 删除分片内所有已过期的键，返回删除的数量，调用方需持有分片写锁
+func (sh *shard[K, V]) sweep() int {
+	if len(sh.expires) == 0 {
+		return 0
+	}
+
+	removed := 0
+	now, next := sh.now(), int64(math.MaxInt64)
+	for key, e := range sh.expires {
+		if e.deadline <= now {
+			value, _ := sh.find(key)
+			sh.dropExpired(key, value)
+			removed++
+		} else {
+			next = min(next, e.deadline)
+		}
+	}
+	// 已过期的键全部删除，Size 恢复无锁读取
+	sh.size.next.Store(next)
+	return removed
+}
@@ -0,0 +1,377 @@
+package cmap
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestPutWithTTL 测试带TTL的写入
+func TestPutWithTTL(t *testing.T) {
+	clock := newFakeClock()
+	m := NewStringHashMap[int](WithClock(clock.Now))
+	m.PutWithTTL("short", 1, time.Second)
+	m.PutWithTTL("forever", 2, 0)
+	m.Put("plain", 3)
+
+	if value, ok := m.Get("short"); !ok || value != 1 {
+		t.Errorf("Get before expiry got (%v, %v), want (1, true)", value, ok)
+	}
+
+	clock.Advance(time.Second)
+
+	if _, ok := m.Get("short"); ok {
+		t.Error("Get should not return expired key")
+	}
+	if _, ok := m.Get("forever"); !ok {
+		t.Error("Key with zero ttl should never expire")
+	}
+	if m.Size() != 2 {
+		t.Errorf("Size should exclude expired keys, got %d, want 2", m.Size())
+	}
+}
+
+// TestDefaultTTL 测试默认TTL
+func TestDefaultTTL(t *testing.T) {
+	clock := newFakeClock()
+	m := NewStringHashMap[int](WithClock(clock.Now), WithDefaultTTL(time.Second))
+	m.Put("a", 1)
+	m.Put("b", 2)
+	m.PutWithTTL("c", 3, time.Hour)
+
+	clock.Advance(time.Second)
+
+	if m.Size() != 1 {
+		t.Errorf("Size got %d, want 1", m.Size())
+	}
+	if keys := m.Keys(); len(keys) != 1 || keys[0] != "c" {
+		t.Errorf("Keys should exclude expired keys, got %v", keys)
+	}
+	if values := m.Values(); len(values) != 1 || values[0] != 3 {
+		t.Errorf("Values should exclude expired values, got %v", values)
+	}
+
+	count := 0
+	m.Range(func(key string, value int) bool {
+		count++
+		return true
+	})
+	if count != 1 {
+		t.Errorf("Range should skip expired keys, visited %d", count)
+	}
+}
+
+// TestTTLExpiredKeyTreatedAsAbsent 测试过期键在原子操作中视为不存在
+func TestTTLExpiredKeyTreatedAsAbsent(t *testing.T) {
+	clock := newFakeClock()
+	m := NewStringHashMap[int](WithClock(clock.Now))
+	m.PutWithTTL("key", 1, time.Second)
+	clock.Advance(time.Second)
+
+	if _, loaded := m.PutIfAbsent("key", 2); loaded {
+		t.Error("PutIfAbsent should treat expired key as absent")
+	}
+	if value, _ := m.Get("key"); value != 2 {
+		t.Errorf("PutIfAbsent should store new value, got %v", value)
+	}
+
+	m.PutWithTTL("gone", 1, time.Second)
+	clock.Advance(time.Second)
+	if _, loaded := m.LoadAndDelete("gone"); loaded {
+		t.Error("LoadAndDelete should treat expired key as absent")
+	}
+}
+
+// TestTTLRemaining 测试剩余存活时间
+func TestTTLRemaining(t *testing.T) {
+	clock := newFakeClock()
+	m := NewStringHashMap[int](WithClock(clock.Now))
+	m.PutWithTTL("key", 1, time.Hour)
+	m.Put("plain", 2)
+
+	clock.Advance(time.Minute)
+	if ttl, ok := m.TTL("key"); !ok || ttl != 59*time.Minute {
+		t.Errorf("TTL got (%v, %v), want (59m, true)", ttl, ok)
+	}
+	if ttl, ok := m.TTL("plain"); !ok || ttl != 0 {
+		t.Errorf("TTL for key without expiry got (%v, %v), want (0, true)", ttl, ok)
+	}
+	if _, ok := m.TTL("missing"); ok {
+		t.Error("TTL for missing key should return false")
+	}
+
+	// 普通写入会移除TTL
+	m.Put("key", 3)
+	if ttl, _ := m.TTL("key"); ttl != 0 {
+		t.Errorf("Put should reset ttl to default, got %v", ttl)
+	}
+}
+
+// TestSweep 测试逐个分片清理过期键，后台清理协程的定时触发见 TestOnExpireJanitor
+func TestSweep(t *testing.T) {
+	clock := newFakeClock()
+	m := NewStringHashMap[int](WithClock(clock.Now), WithShardCount(4))
+
+	for i := 0; i < 100; i++ {
+		m.PutWithTTL(string(rune('a'+i%26))+string(rune('a'+i/26)), i, time.Second)
+	}
+	m.Put("live", 1)
+	clock.Advance(time.Second)
+
+	if removed := m.sweep(); removed != 100 {
+		t.Errorf("Sweep removed %d keys, want 100", removed)
+	}
+	physical := 0
+	for i := range m.shards {
+		physical += m.shards[i].m.Size()
+	}
+	if physical != 1 {
+		t.Errorf("Sweep should remove expired keys, %d keys left, want 1", physical)
+	}
+}
+
+// TestClose 测试重复关闭
+func TestClose(t *testing.T) {
+	m := NewStringHashMap[int](WithJanitor(time.Millisecond))
+	if err := m.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("Second Close failed: %v", err)
+	}
+
+	// 未启动清理协程的映射也可以关闭
+	if err := NewStringHashMap[int]().Close(); err != nil {
+		t.Errorf("Close without janitor failed: %v", err)
+	}
+}
+
+// TestTTLSaveToFile 测试过期键不写入文件
+func TestTTLSaveToFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "ttl.json")
+
+	clock := newFakeClock()
+	m := NewStringHashMap[int](WithClock(clock.Now))
+	m.PutWithTTL("expired", 1, time.Second)
+	m.Put("live", 2)
+	clock.Advance(time.Second)
+
+	if err := m.SaveToFile(filename); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+	if _, err := os.Stat(filename); err != nil {
+		t.Fatalf("Saved file missing: %v", err)
+	}
+
+	loaded := NewStringHashMap[int]()
+	if err := loaded.LoadFromFile(filename); err != nil {
+		t.Fatalf("LoadFromFile failed: %v", err)
+	}
+	if loaded.Size() != 1 {
+		t.Errorf("Loaded map should only contain live key, got size %d", loaded.Size())
+	}
+	if _, ok := loaded.Get("expired"); ok {
+		t.Error("Expired key should not be saved")
+	}
+}
+
+// fakeClock 可手动推进的测试时钟
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1700000000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// TestClock 测试自定义时钟
+func TestClock(t *testing.T) {
+	clock := newFakeClock()
+	m := NewStringHashMap[int](WithClock(clock.Now), WithDefaultTTL(time.Minute))
+	m.Put("key", 1)
+
+	clock.Advance(59 * time.Second)
+	if _, ok := m.Get("key"); !ok {
+		t.Error("Key should not expire before ttl")
+	}
+
+	clock.Advance(time.Second)
+	if _, ok := m.Get("key"); ok {
+		t.Error("Key should expire after ttl")
+	}
+}
+
+// TestSizeWithTTL 测试含TTL键的分片在没有键过期时无锁读取数量
+func TestSizeWithTTL(t *testing.T) {
+	clock := newFakeClock()
+	m := NewStringHashMap[int](WithClock(clock.Now), WithShardCount(1))
+	m.PutWithTTL("short", 1, time.Minute)
+	m.PutWithTTL("long", 2, time.Hour)
+	m.Put("forever", 3)
+
+	// lockFree 检查持有分片写锁时 Size 能否在wait内返回；加锁时永远不会返回，wait 只影响预期加锁时的耗时
+	lockFree := func(wait time.Duration) bool {
+		sh := &m.shards[0]
+		sh.mu.Lock()
+		defer sh.mu.Unlock()
+		done := make(chan struct{})
+		go func() {
+			m.Size()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return true
+		case <-time.After(wait):
+			return false
+		}
+	}
+
+	if !lockFree(time.Second) {
+		t.Error("Size should not lock before any key expires")
+	}
+	if size := m.Size(); size != 3 {
+		t.Errorf("Size got %d, want 3", size)
+	}
+
+	clock.Advance(time.Minute)
+	if size := m.Size(); size != 2 {
+		t.Errorf("Size after expiry got %d, want 2", size)
+	}
+	if lockFree(50 * time.Millisecond) {
+		t.Error("Size should lock while an expired key is not removed")
+	}
+
+	m.sweep()
+	if !lockFree(time.Second) {
+		t.Error("Size should not lock after expired keys are removed")
+	}
+	if size := m.Size(); size != 2 {
+		t.Errorf("Size after sweep got %d, want 2", size)
+	}
+
+	clock.Advance(time.Hour)
+	if size := m.Size(); size != 1 {
+		t.Errorf("Size after second expiry got %d, want 1", size)
+	}
+}
+
+// TestSlidingExpiration 测试滑动过期
+func TestSlidingExpiration(t *testing.T) {
+	clock := newFakeClock()
+	m := NewStringHashMap[string](WithClock(clock.Now), WithSlidingExpiration())
+	m.PutWithTTL("session", "alice", 10*time.Minute)
+	m.PutWithTTL("idle", "bob", 10*time.Minute)
+
+	// 每次访问都会延长存活时间
+	for i := 0; i < 5; i++ {
+		clock.Advance(8 * time.Minute)
+		if _, ok := m.Get("session"); !ok {
+			t.Fatalf("Session should be extended by Get, expired at step %d", i)
+		}
+	}
+
+	if _, ok := m.Get("idle"); ok {
+		t.Error("Idle key should expire without access")
+	}
+
+	clock.Advance(10 * time.Minute)
+	if _, ok := m.Get("session"); ok {
+		t.Error("Session should expire after ttl without access")
+	}
+}
+
+// TestOnExpire 测试过期回调
+func TestOnExpire(t *testing.T) {
+	clock := newFakeClock()
+	var mu sync.Mutex
+	expired := make(map[string]int)
+
+	var m *Map[string, int]
+	m = NewStringHashMap[int](
+		WithClock(clock.Now),
+		WithOnExpire(func(key string, value int) {
+			// 回调在锁外执行，可以访问映射
+			m.Size()
+			mu.Lock()
+			expired[key] = value
+			mu.Unlock()
+		}),
+	)
+
+	m.PutWithTTL("lazy", 1, time.Second)
+	m.PutWithTTL("swept", 2, time.Second)
+	m.PutWithTTL("overwritten", 3, time.Second)
+	m.PutWithTTL("live", 4, time.Hour)
+	clock.Advance(2 * time.Second)
+
+	// 惰性过期
+	m.Get("lazy")
+	// 覆盖已过期的键
+	m.Put("overwritten", 30)
+	// 清理
+	if removed := m.sweep(); removed != 1 {
+		t.Errorf("sweep removed %d keys, want 1", removed)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]int{"lazy": 1, "swept": 2, "overwritten": 3}
+	if len(expired) != len(want) {
+		t.Errorf("OnExpire got %v, want %v", expired, want)
+	}
+	for key, value := range want {
+		if expired[key] != value {
+			t.Errorf("OnExpire for %s got %v, want %v", key, expired[key], value)
+		}
+	}
+}
+
+// TestOnExpireJanitor 测试清理协程触发过期回调
+func TestOnExpireJanitor(t *testing.T) {
+	clock := newFakeClock()
+	done := make(chan string, 1)
+	m := NewStringHashMap[int](
+		WithClock(clock.Now),
+		WithJanitor(time.Millisecond),
+		WithOnExpire(func(key string, value int) { done <- key }),
+	)
+	defer m.Close()
+
+	m.PutWithTTL("key", 1, time.Second)
+	clock.Advance(time.Minute)
+
+	select {
+	case key := <-done:
+		if key != "key" {
+			t.Errorf("OnExpire got key %s, want key", key)
+		}
+	case <-time.After(time.Second):
+		t.Error("Janitor should trigger OnExpire")
+	}
+}
+
+// TestOnExpireTypeMismatch 测试回调类型不匹配
+func TestOnExpireTypeMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Mismatched OnExpire type should panic")
+		}
+	}()
+	NewStringHashMap[int](WithOnExpire(func(key int, value int) {}))
+}
@@ -0,0 +1,199 @@
+package cmap
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Tx 多键事务，只能在 Update 的回调内使用
+type Tx[K cmp.Ordered, V any] struct {
+	m       *Map[K, V]
+	held    map[uint32]bool // 已持有写锁的分片
+	max     int64           // 已持有写锁的最大分片下标，-1表示未持有
+	seen    []uint32        // 事务访问过的分片，重新执行时预先按顺序加锁
+	writes  map[K]txWrite[V]
+	keys    []K  // 首次写入的顺序，提交时按该顺序写入
+	restart bool // 需要按顺序重新加锁，本次执行的结果将被丢弃
+}
+
+// txWrite 事务内缓存的写操作
+type txWrite[V any] struct {
+	value  V
+	remove bool
+}
+
+// Update 在事务内原子地读写多个键
+//
+// 事务按需锁定涉及的分片：只会阻塞等待下标更大的分片，需要更小下标的分片且无法立即获取时，
+// 释放所有锁后按分片下标升序重新加锁并重新执行 fn，因此 fn 可能被调用多次，不应有事务之外的副作用。
+// 需要重新执行时，本次执行中后续的 Get 返回零值和false、Put 与 Remove 不生效，fn 的返回值和 panic 都会被丢弃。
+// 写操作先缓存在事务中，fn 返回nil时在持有所有相关分片锁的情况下一次性提交；fn 返回错误、panic
+// 或写入被 WithBeforePut 拒绝时回滚，映射保持不变并返回该错误。fn 内不得访问当前映射的其他方法，否则会死锁。
+func (m *Map[K, V]) Update(fn func(tx *Tx[K, V]) error) error {
+	var need []uint32
+	for {
+		tx := &Tx[K, V]{
+			m:      m,
+			held:   make(map[uint32]bool),
+			max:    -1,
+			writes: make(map[K]txWrite[V]),
+		}
+		for _, i := range need {
+			tx.lock(i)
+		}
+
+		err, restart := tx.run(fn)
+		if restart {
+			tx.rollback()
+			need = tx.seen
+			slices.Sort(need)
+			continue
+		}
+		if err == nil {
+			err = tx.validate()
+		}
+		if err != nil {
+			tx.rollback()
+			return err
+		}
+		tx.commit()
+		return nil
+	}
+}
+
+// Get 获取值，包括事务内尚未提交的写入
+func (tx *Tx[K, V]) Get(key K) (value V, found bool) {
+	if w, ok := tx.writes[key]; ok {
+		if w.remove {
+			return value, false
+		}
+		return w.value, true
+	}
+	sh, ok := tx.shard(key)
+	if !ok {
+		return value, false
+	}
+	return sh.get(key)
+}
+
+// Put 写入键值对，提交时生效
+func (tx *Tx[K, V]) Put(key K, value V) {
+	if _, ok := tx.shard(key); ok {
+		tx.write(key, txWrite[V]{value: value})
+	}
+}
+
+// Remove 删除键，提交时生效
+func (tx *Tx[K, V]) Remove(key K) {
+	if _, ok := tx.shard(key); ok {
+		tx.write(key, txWrite[V]{remove: true})
+	}
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// run 执行回调，需要按顺序重新加锁时restart为true
+//
+// 重新执行由 tx.restart 标记而不是 panic 中断回调，fn 内的 recover 无法吞掉它。
+func (tx *Tx[K, V]) run(fn func(tx *Tx[K, V]) error) (err error, restart bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if tx.restart {
+				// 读到的是零值，panic 可能由此引起，重新执行即可
+				restart = true
+				return
+			}
+			tx.rollback()
+			panic(r)
+		}
+	}()
+	err = fn(tx)
+	return err, tx.restart
+}
+
+// write 缓存写操作
+func (tx *Tx[K, V]) write(key K, w txWrite[V]) {
+	if _, ok := tx.writes[key]; !ok {
+		tx.keys = append(tx.keys, key)
+	}
+	tx.writes[key] = w
+}
+
+// shard 获取键所在的分片，必要时加锁；需要重新执行时返回false
+func (tx *Tx[K, V]) shard(key K) (*shard[K, V], bool) {
+	i := tx.m.hasher.Hash(key) & tx.m.mask
+	if !tx.held[i] {
+		if !slices.Contains(tx.seen, i) {
+			tx.seen = append(tx.seen, i)
+		}
+		switch {
+		case tx.restart:
+			// 只记录分片，重新执行时一并预先加锁
+			return nil, false
+		case int64(i) > tx.max:
+			tx.lock(i)
+		case tx.m.shards[i].mu.TryLock():
+			// 无需等待即可获取下标更小的分片，不会产生死锁
+			tx.held[i] = true
+		default:
+			tx.restart = true
+			return nil, false
+		}
+	}
+	return &tx.m.shards[i], true
+}
+
+// lock 阻塞获取分片写锁
+func (tx *Tx[K, V]) lock(i uint32) {
+	if tx.held[i] {
+		return
+	}
+	tx.m.shards[i].mu.Lock()
+	tx.held[i] = true
+	tx.max = max(tx.max, int64(i))
+}
+
+// validate 校验所有待写入的值
+func (tx *Tx[K, V]) validate() error {
+	for _, key := range tx.keys {
+		if w := tx.writes[key]; !w.remove {
+			if err := tx.m.check(key, w.value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// commit 按写入顺序提交，按分片下标升序释放锁后再派发回调
+func (tx *Tx[K, V]) commit() {
+	for _, key := range tx.keys {
+		sh := &tx.m.shards[tx.m.hasher.Hash(key)&tx.m.mask]
+		if w := tx.writes[key]; w.remove {
+			sh.remove(key)
+		} else {
+			sh.put(key, w.value)
+		}
+	}
+
+	held := make([]uint32, 0, len(tx.held))
+	for i := range tx.held {
+		held = append(held, i)
+	}
+	slices.Sort(held)
+	var changes []change[K, V]
+	for _, i := range held {
+		changes = append(changes, tx.m.release(&tx.m.shards[i])...)
+	}
+	tx.held = nil
+	tx.m.dispatch(changes)
+	tx.m.trim(nil)
+}
+
+// rollback 丢弃所有写操作并释放锁
+func (tx *Tx[K, V]) rollback() {
+	for i := range tx.held {
+		tx.m.shards[i].mu.Unlock()
+	}
+	tx.held = nil
+}
@@ -0,0 +1,296 @@
+package cmap
+
+import (
+	"cmp"
+	"errors"
+	"math/rand"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errInsufficient = errors.New("insufficient balance")
+
+// transfer 在事务内转账
+func transfer(m *Map[string, int], from, to string, amount int) error {
+	return m.Update(func(tx *Tx[string, int]) error {
+		balance, _ := tx.Get(from)
+		if balance < amount {
+			return errInsufficient
+		}
+		target, _ := tx.Get(to)
+		tx.Put(from, balance-amount)
+		tx.Put(to, target+amount)
+		return nil
+	})
+}
+
+// TestUpdate 测试事务提交
+func TestUpdate(t *testing.T) {
+	m := NewStringHashMap[int]()
+	m.PutAll(map[string]int{"alice": 100, "bob": 50})
+
+	if err := transfer(m, "alice", "bob", 30); err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if value, _ := m.Get("alice"); value != 70 {
+		t.Errorf("alice got %d, want 70", value)
+	}
+	if value, _ := m.Get("bob"); value != 80 {
+		t.Errorf("bob got %d, want 80", value)
+	}
+
+	// 事务内可以读取尚未提交的写入
+	err := m.Update(func(tx *Tx[string, int]) error {
+		tx.Put("carol", 1)
+		tx.Remove("bob")
+		if value, ok := tx.Get("carol"); !ok || value != 1 {
+			t.Errorf("Tx Get should see pending put, got (%v, %v)", value, ok)
+		}
+		if _, ok := tx.Get("bob"); ok {
+			t.Error("Tx Get should see pending remove")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, ok := m.Get("bob"); ok {
+		t.Error("Committed remove should delete bob")
+	}
+	if !m.IsDirty() {
+		t.Error("Committed transaction should mark map dirty")
+	}
+}
+
+// TestUpdateRollback 测试事务回滚
+func TestUpdateRollback(t *testing.T) {
+	errReject := errors.New("reject")
+	m := NewStringHashMap[int](WithBeforePut(func(key string, value int) error {
+		if value < 0 {
+			return errReject
+		}
+		return nil
+	}))
+	m.PutAll(map[string]int{"alice": 10, "bob": 0})
+
+	if err := transfer(m, "alice", "bob", 20); !errors.Is(err, errInsufficient) {
+		t.Errorf("transfer error got %v, want %v", err, errInsufficient)
+	}
+
+	// 写入被拒绝时整个事务回滚
+	err := m.Update(func(tx *Tx[string, int]) error {
+		tx.Put("alice", 5)
+		tx.Put("bob", -1)
+		return nil
+	})
+	if !errors.Is(err, errReject) {
+		t.Errorf("Update error got %v, want %v", err, errReject)
+	}
+
+	// panic 时回滚并释放锁
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Panic should propagate")
+			}
+		}()
+		_ = m.Update(func(tx *Tx[string, int]) error {
+			tx.Put("alice", 0)
+			panic("boom")
+		})
+	}()
+
+	if value, _ := m.Get("alice"); value != 10 {
+		t.Errorf("Rolled back transaction should keep alice=10, got %d", value)
+	}
+	if value, _ := m.Get("bob"); value != 0 {
+		t.Errorf("Rolled back transaction should keep bob=0, got %d", value)
+	}
+	m.Put("alice", 1)
+}
+
+// TestUpdateConcurrent 测试并发转账总额不变
+func TestUpdateConcurrent(t *testing.T) {
+	const accounts = 20
+	const initial = 1000
+	m := NewStringHashMap[int](WithShardCount(8))
+	for i := 0; i < accounts; i++ {
+		m.Put("account"+strconv.Itoa(i), initial)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < 500; i++ {
+				from := "account" + strconv.Itoa(r.Intn(accounts))
+				to := "account" + strconv.Itoa(r.Intn(accounts))
+				if from == to {
+					continue
+				}
+				_ = transfer(m, from, to, r.Intn(50))
+			}
+		}(int64(w))
+	}
+
+	// 并发检查一致快照中的总额
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			total := 0
+			for _, value := range m.Snapshot().Values() {
+				total += value
+			}
+			if total != accounts*initial {
+				t.Errorf("Snapshot total got %d, want %d", total, accounts*initial)
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	<-done
+	total := 0
+	for _, value := range m.Values() {
+		total += value
+	}
+	if total != accounts*initial {
+		t.Errorf("Total got %d, want %d", total, accounts*initial)
+	}
+}
+
+// shardPair 选取 high 所在分片下标大于 low 的两个键
+func shardPair(m *Map[string, int]) (low, high string) {
+	for i := 0; high == ""; i++ {
+		key := "key" + strconv.Itoa(i)
+		if low == "" {
+			low = key
+		} else if m.hasher.Hash(key)&m.mask != m.hasher.Hash(low)&m.mask {
+			high = key
+			if m.hasher.Hash(high)&m.mask < m.hasher.Hash(low)&m.mask {
+				low, high = high, low
+			}
+		}
+	}
+	return low, high
+}
+
+// TestUpdateRestart 测试需要更小下标分片时重新执行
+func TestUpdateRestart(t *testing.T) {
+	m := NewStringHashMap[int](WithShardCount(4))
+	low, high := shardPair(m)
+
+	// 占用 low 所在分片，迫使事务先锁 high 后无法立即获取 low
+	lowShard := m.getShard(low)
+	lowShard.mu.Lock()
+	calls := 0
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- m.Update(func(tx *Tx[string, int]) error {
+			calls++
+			tx.Put(high, 1)
+			if calls == 1 {
+				close(started)
+			}
+			tx.Put(low, 1)
+			return nil
+		})
+	}()
+	<-started
+	time.Sleep(20 * time.Millisecond)
+	lowShard.mu.Unlock()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if calls < 2 {
+		t.Errorf("Transaction should restart, called %d times", calls)
+	}
+	if m.Size() != 2 {
+		t.Errorf("Size got %d, want 2", m.Size())
+	}
+}
+
+// TestUpdateRestartRecover 测试 fn 内的 recover 不影响重新执行
+func TestUpdateRestartRecover(t *testing.T) {
+	m := NewStringHashMap[int](WithShardCount(4))
+	low, high := shardPair(m)
+	m.Put(low, 1)
+
+	lowShard := m.getShard(low)
+	lowShard.mu.Lock()
+	calls := 0
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- m.Update(func(tx *Tx[string, int]) error {
+			calls++
+			tx.Put(high, 1)
+			if calls == 1 {
+				close(started)
+			}
+			func() {
+				defer func() { recover() }()
+				value, _ := tx.Get(low)
+				tx.Put(low, 10/value)
+			}()
+			return nil
+		})
+	}()
+	<-started
+	time.Sleep(20 * time.Millisecond)
+	lowShard.mu.Unlock()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Transaction should restart once, called %d times", calls)
+	}
+	if value, _ := m.Get(low); value != 10 {
+		t.Errorf("low got %d, want 10", value)
+	}
+	if value, _ := m.Get(high); value != 1 {
+		t.Errorf("high got %d, want 1", value)
+	}
+}
+
+// TestUpdateHookOrder 测试提交后按分片下标顺序派发回调
+func TestUpdateHookOrder(t *testing.T) {
+	var puts []string
+	m := NewStringHashMap[int](WithOnPut(func(key string, oldValue, newValue int, replaced bool) {
+		puts = append(puts, key)
+	}))
+
+	keys := make([]string, 50)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+	want := slices.Clone(keys)
+	slices.SortStableFunc(want, func(a, b string) int {
+		return cmp.Compare(m.hasher.Hash(a)&m.mask, m.hasher.Hash(b)&m.mask)
+	})
+
+	for round := 0; round < 5; round++ {
+		puts = nil
+		err := m.Update(func(tx *Tx[string, int]) error {
+			for _, key := range keys {
+				tx.Put(key, round)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if !slices.Equal(puts, want) {
+			t.Fatalf("OnPut order got %v, want %v", puts, want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package cmap
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+)
+
+// ErrConflict 乐观事务重试次数用尽后仍然冲突
+var ErrConflict = errors.New("cmap: transaction conflict")
+
+// defaultTxnRetries 乐观事务默认的最大重试次数
+const defaultTxnRetries = 10
+
+// Txn 乐观事务，只能在 OptimisticUpdate 的回调内使用
+type Txn[K cmp.Ordered, V any] struct {
+	m        *Map[K, V]
+	reads    map[uint32]uint64 // 读取过的分片及读取时的版本号
+	writes   map[K]txWrite[V]
+	keys     []K      // 首次写入的顺序，提交时按该顺序写入
+	conflict bool     // 执行期间已发现冲突
+	shards   []uint32 // 提交时需要锁定的分片
+}
+
+// OptimisticUpdate 以乐观方式在事务内读写多个键
+//
+// 与 Update 不同，fn 执行期间不持有任何锁：读操作记录所在分片的版本号，写操作缓存在事务中。
+// fn 返回nil后按分片下标升序锁定相关分片，校验读取过的分片版本号未变化后一次性提交；
+// 校验失败时重新执行 fn，超过重试次数（见 WithTxnRetries）返回 ErrConflict。
+// 冲突按分片检测，同一分片内其他键的修改也会导致重试。fn 返回错误或写入被 WithBeforePut 拒绝时不提交并返回该错误。
+func (m *Map[K, V]) OptimisticUpdate(fn func(txn *Txn[K, V]) error) error {
+	retries := m.opts.TxnRetries
+	if retries <= 0 {
+		retries = defaultTxnRetries
+	}
+
+	for attempt := 0; attempt <= retries; attempt++ {
+		txn := &Txn[K, V]{
+			m:      m,
+			reads:  make(map[uint32]uint64),
+			writes: make(map[K]txWrite[V]),
+		}
+		if err := fn(txn); err != nil {
+			return err
+		}
+		if txn.conflict {
+			continue
+		}
+		if err := txn.validate(); err != nil {
+			return err
+		}
+		if txn.commit() {
+			return nil
+		}
+	}
+	return ErrConflict
+}
+
+// Get 获取值，包括事务内尚未提交的写入
+func (txn *Txn[K, V]) Get(key K) (value V, found bool) {
+	if w, ok := txn.writes[key]; ok {
+		if w.remove {
+			return value, false
+		}
+		return w.value, true
+	}
+
+	i := txn.m.hasher.Hash(key) & txn.m.mask
+	sh := &txn.m.shards[i]
+	sh.mu.RLock()
+	value, found = sh.get(key)
+	version := sh.version.Load()
+	sh.mu.RUnlock()
+
+	if read, ok := txn.reads[i]; !ok {
+		txn.reads[i] = version
+	} else if read != version {
+		// 同一分片两次读取之间被修改，事务已无法提交
+		txn.conflict = true
+	}
+	return
+}
+
+// Put 写入键值对，提交时生效
+func (txn *Txn[K, V]) Put(key K, value V) {
+	txn.write(key, txWrite[V]{value: value})
+}
+
+// Remove 删除键，提交时生效
+func (txn *Txn[K, V]) Remove(key K) {
+	txn.write(key, txWrite[V]{remove: true})
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// write 缓存写操作
+func (txn *Txn[K, V]) write(key K, w txWrite[V]) {
+	if _, ok := txn.writes[key]; !ok {
+		txn.keys = append(txn.keys, key)
+	}
+	txn.writes[key] = w
+}
+
+// validate 校验所有待写入的值
+func (txn *Txn[K, V]) validate() error {
+	for _, key := range txn.keys {
+		if w := txn.writes[key]; !w.remove {
+			if err := txn.m.check(key, w.value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// commit 锁定相关分片并校验版本号，未冲突时提交并返回true
+func (txn *Txn[K, V]) commit() bool {
+	seen := make(map[uint32]bool, len(txn.reads))
+	for i := range txn.reads {
+		seen[i] = true
+	}
+	for _, key := range txn.keys {
+		seen[txn.m.hasher.Hash(key)&txn.m.mask] = true
+	}
+	for i := range seen {
+		txn.shards = append(txn.shards, i)
+	}
+	slices.Sort(txn.shards)
+
+	for _, i := range txn.shards {
+		txn.m.shards[i].mu.Lock()
+	}
+	for i, version := range txn.reads {
+		if txn.m.shards[i].version.Load() != version {
+			for _, j := range txn.shards {
+				txn.m.shards[j].mu.Unlock()
+			}
+			return false
+		}
+	}
+
+	for _, key := range txn.keys {
+		sh := txn.m.getShard(key)
+		if w := txn.writes[key]; w.remove {
+			sh.remove(key)
+		} else {
+			sh.put(key, w.value)
+		}
+	}
+
+	var changes []change[K, V]
+	for _, i := range txn.shards {
+		changes = append(changes, txn.m.release(&txn.m.shards[i])...)
+	}
+	txn.m.dispatch(changes)
+	txn.m.trim(nil)
+	return true
+}
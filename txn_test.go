@@ -0,0 +1,155 @@
+package cmap
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// transferOptimistic 在乐观事务内转账
+func transferOptimistic(m *Map[string, int], from, to string, amount int) error {
+	return m.OptimisticUpdate(func(txn *Txn[string, int]) error {
+		balance, _ := txn.Get(from)
+		if balance < amount {
+			return errInsufficient
+		}
+		target, _ := txn.Get(to)
+		txn.Put(from, balance-amount)
+		txn.Put(to, target+amount)
+		return nil
+	})
+}
+
+// TestOptimisticUpdate 测试乐观事务提交
+func TestOptimisticUpdate(t *testing.T) {
+	m := NewStringHashMap[int]()
+	m.PutAll(map[string]int{"alice": 100, "bob": 50})
+
+	if err := transferOptimistic(m, "alice", "bob", 30); err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if value, _ := m.Get("alice"); value != 70 {
+		t.Errorf("alice got %d, want 70", value)
+	}
+	if value, _ := m.Get("bob"); value != 80 {
+		t.Errorf("bob got %d, want 80", value)
+	}
+
+	// fn 返回错误时不提交
+	if err := transferOptimistic(m, "alice", "bob", 1000); !errors.Is(err, errInsufficient) {
+		t.Errorf("transfer error got %v, want %v", err, errInsufficient)
+	}
+	if value, _ := m.Get("alice"); value != 70 {
+		t.Errorf("Failed transaction should keep alice=70, got %d", value)
+	}
+
+	// 事务内可以读取尚未提交的写入
+	err := m.OptimisticUpdate(func(txn *Txn[string, int]) error {
+		txn.Put("carol", 1)
+		txn.Remove("bob")
+		if value, ok := txn.Get("carol"); !ok || value != 1 {
+			t.Errorf("Txn Get should see pending put, got (%v, %v)", value, ok)
+		}
+		if _, ok := txn.Get("bob"); ok {
+			t.Error("Txn Get should see pending remove")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("OptimisticUpdate failed: %v", err)
+	}
+	if _, ok := m.Get("bob"); ok {
+		t.Error("Committed remove should delete bob")
+	}
+	if value, _ := m.Get("carol"); value != 1 {
+		t.Errorf("carol got %d, want 1", value)
+	}
+}
+
+// TestOptimisticUpdateConflict 测试冲突时重试
+func TestOptimisticUpdateConflict(t *testing.T) {
+	m := NewStringHashMap[int]()
+	m.Put("counter", 0)
+
+	// 第一次执行期间有其他写入，事务应重新执行并基于最新值提交
+	calls := 0
+	err := m.OptimisticUpdate(func(txn *Txn[string, int]) error {
+		calls++
+		value, _ := txn.Get("counter")
+		if calls == 1 {
+			m.Put("counter", 10)
+		}
+		txn.Put("counter", value+1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("OptimisticUpdate failed: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Transaction should run twice, called %d times", calls)
+	}
+	if value, _ := m.Get("counter"); value != 11 {
+		t.Errorf("counter got %d, want 11", value)
+	}
+
+	// 持续冲突时重试次数用尽返回 ErrConflict
+	m = NewStringHashMap[int](WithTxnRetries(2))
+	calls = 0
+	err = m.OptimisticUpdate(func(txn *Txn[string, int]) error {
+		calls++
+		value, _ := txn.Get("counter")
+		m.Put("counter", value+100)
+		txn.Put("counter", value+1)
+		return nil
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("OptimisticUpdate error got %v, want %v", err, ErrConflict)
+	}
+	if calls != 3 {
+		t.Errorf("Transaction should run 3 times, called %d times", calls)
+	}
+	if value, _ := m.Get("counter"); value != 300 {
+		t.Errorf("counter got %d, want 300", value)
+	}
+}
+
+// TestOptimisticUpdateConcurrent 测试并发转账总额不变
+func TestOptimisticUpdateConcurrent(t *testing.T) {
+	const accounts = 20
+	const initial = 1000
+	m := NewStringHashMap[int](WithShardCount(8), WithTxnRetries(1000))
+	for i := 0; i < accounts; i++ {
+		m.Put("account"+strconv.Itoa(i), initial)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < 500; i++ {
+				from := "account" + strconv.Itoa(r.Intn(accounts))
+				to := "account" + strconv.Itoa(r.Intn(accounts))
+				if from == to {
+					continue
+				}
+				if err := transferOptimistic(m, from, to, r.Intn(50)); errors.Is(err, ErrConflict) {
+					t.Errorf("transfer failed: %v", err)
+					return
+				}
+			}
+		}(int64(w))
+	}
+	wg.Wait()
+
+	total := 0
+	for _, value := range m.Values() {
+		total += value
+	}
+	if total != accounts*initial {
+		t.Errorf("Total got %d, want %d", total, accounts*initial)
+	}
+}
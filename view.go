@@ -0,0 +1,69 @@
+package cmap
+
+// shardView 只读优化模式下分片发布的不可变数据，生成后不再修改，读取时无需加锁
+type shardView[K comparable, V any] struct {
+	items     map[K]V
+	deadlines map[K]int64 // 设置了TTL的键的过期时刻
+	version   uint64      // 生成视图时的分片版本号
+}
+
+// lookup 查找键，返回值、键是否存在以及是否已过期但尚未删除
+//
+// 开启 WithReadOptimized 且只读视图仍与分片版本号一致时直接读取视图，不获取任何锁；
+// 否则在读锁内查找，并在视图失效后的读取次数达到分片键数量时重新生成视图。
+func (sh *shard[K, V]) lookup(key K) (value V, found, expired bool) {
+	if sh.views {
+		if v := sh.view.Load(); v != nil && v.version == sh.version.Load() {
+			value, found = v.items[key]
+			if found {
+				deadline, ok := v.deadlines[key]
+				expired = ok && deadline <= sh.now()
+			}
+			return
+		}
+	}
+
+	sh.mu.RLock()
+	value, found = sh.find(key)
+	expired = found && sh.expired(key, sh.now())
+	if sh.views {
+		sh.miss()
+	}
+	sh.mu.RUnlock()
+	return
+}
+
+// miss 记录一次视图失效后的读取，类似 sync.Map 在未命中次数达到数据量时提升 dirty，
+// 重新生成视图的开销分摊到多次读取上，调用方需持有分片读锁
+func (sh *shard[K, V]) miss() {
+	n := sh.misses.Add(1)
+	if n < uint64(sh.entries()) || !sh.misses.CompareAndSwap(n, 0) {
+		return
+	}
+
+	v := &shardView[K, V]{
+		items:   make(map[K]V, sh.entries()),
+		version: sh.version.Load(),
+	}
+	sh.scan(func(key K, value V) bool {
+		v.items[key] = value
+		return true
+	})
+	if len(sh.expires) > 0 {
+		v.deadlines = make(map[K]int64, len(sh.expires))
+		for key, e := range sh.expires {
+			v.deadlines[key] = e.deadline
+		}
+	}
+
+	// 多个读者可能同时生成视图，只保留版本更新的视图
+	for {
+		old := sh.view.Load()
+		if old != nil && old.version >= v.version {
+			return
+		}
+		if sh.view.CompareAndSwap(old, v) {
+			return
+		}
+	}
+}
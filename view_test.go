@@ -0,0 +1,134 @@
+package cmap
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// warm 反复读取直到键所在分片生成与当前版本一致的只读视图
+func warm(t *testing.T, m *Map[string, int], key string) *shard[string, int] {
+	t.Helper()
+	sh := m.getShard(key)
+	for i := 0; i < 1000; i++ {
+		if v := sh.view.Load(); v != nil && v.version == sh.version.Load() {
+			return sh
+		}
+		m.Get(key)
+	}
+	t.Fatal("View should be published after repeated reads")
+	return nil
+}
+
+// TestReadOptimized 测试只读视图的发布与失效
+func TestReadOptimized(t *testing.T) {
+	m := NewStringHashMap[int](WithReadOptimized(), WithShardCount(4))
+	for i := 0; i < 20; i++ {
+		m.Put("key"+strconv.Itoa(i), i)
+	}
+
+	sh := warm(t, m, "key1")
+	if value, ok := m.Get("key1"); !ok || value != 1 {
+		t.Errorf("Get got (%v, %v), want (1, true)", value, ok)
+	}
+
+	// 写入后视图失效，立即读到新值
+	m.Put("key1", 100)
+	if v := sh.view.Load(); v.version == sh.version.Load() {
+		t.Error("Write should invalidate view")
+	}
+	if value, _ := m.Get("key1"); value != 100 {
+		t.Errorf("Get after Put got %d, want 100", value)
+	}
+	m.Remove("key1")
+	if _, ok := m.Get("key1"); ok {
+		t.Error("Get after Remove should miss")
+	}
+
+	warm(t, m, "key1")
+	if _, ok := m.Get("key1"); ok {
+		t.Error("Rebuilt view should not contain removed key")
+	}
+
+	// 开启淘汰策略时 Get 需要写锁，不使用只读视图
+	m = NewStringHashMap[int](WithReadOptimized(), WithCapacity(10))
+	if m.shards[0].views {
+		t.Error("View should be disabled with eviction policy")
+	}
+}
+
+// TestReadOptimizedExpire 测试只读视图中的过期键
+func TestReadOptimizedExpire(t *testing.T) {
+	clock := newFakeClock()
+	var expired []string
+	m := NewStringHashMap[int](
+		WithReadOptimized(),
+		WithClock(clock.Now),
+		WithOnExpire(func(key string, value int) { expired = append(expired, key) }),
+	)
+	m.PutWithTTL("key", 1, time.Minute)
+	warm(t, m, "key")
+
+	clock.Advance(time.Minute)
+	if _, ok := m.Get("key"); ok {
+		t.Error("Expired key should not be visible through view")
+	}
+	if len(expired) != 1 || expired[0] != "key" {
+		t.Errorf("OnExpire got %v, want [key]", expired)
+	}
+	if m.Size() != 0 {
+		t.Errorf("Expired key should be removed, size %d", m.Size())
+	}
+}
+
+// TestReadOptimizedConcurrent 测试并发读写时读取到的值单调递增
+func TestReadOptimizedConcurrent(t *testing.T) {
+	m := NewStringHashMap[int](WithReadOptimized(), WithShardCount(4))
+	const keys = 16
+	for i := 0; i < keys; i++ {
+		m.Put("key"+strconv.Itoa(i), 0)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for n := 1; n <= 500; n++ {
+			for i := 0; i < keys; i++ {
+				m.Put("key"+strconv.Itoa(i), n)
+			}
+		}
+	}()
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := make([]int, keys)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for i := 0; i < keys; i++ {
+					value, _ := m.Get("key" + strconv.Itoa(i))
+					if value < last[i] {
+						t.Errorf("key%d went backwards: %d after %d", i, value, last[i])
+						return
+					}
+					last[i] = value
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < keys; i++ {
+		if value, _ := m.Get("key" + strconv.Itoa(i)); value != 500 {
+			t.Errorf("key%d got %d, want 500", i, value)
+		}
+	}
+}
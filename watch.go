@@ -0,0 +1,318 @@
+package cmap
+
+import (
+	"cmp"
+	"context"
+	"sync"
+)
+
+// EventOp 事件类型
+type EventOp uint8
+
+const (
+	EventPut    EventOp = iota + 1 // 键被写入（包括 GetOrLoad 加载的值）
+	EventRemove                    // 键被删除
+	EventClear                     // 映射被清空，事件不携带键值
+	EventExpire                    // 键过期
+	EventEvict                     // 键因超出容量或成本上限被淘汰
+)
+
+// String 返回事件类型名称
+func (op EventOp) String() string {
+	switch op {
+	case EventPut:
+		return "put"
+	case EventRemove:
+		return "remove"
+	case EventClear:
+		return "clear"
+	case EventExpire:
+		return "expire"
+	case EventEvict:
+		return "evict"
+	default:
+		return "unknown"
+	}
+}
+
+// Event 映射的变更事件
+type Event[K cmp.Ordered, V any] struct {
+	Op       EventOp
+	Key      K
+	OldValue V    // 变更前的值，HasOld为false时为零值
+	NewValue V    // 写入的新值，仅 EventPut 有效
+	HasOld   bool // 变更前键是否存在
+}
+
+// OverflowPolicy 订阅者缓冲区已满时的处理策略
+type OverflowPolicy uint8
+
+const (
+	OverflowDrop       OverflowPolicy = iota // 丢弃新事件，不阻塞写操作
+	OverflowBlock                            // 阻塞写操作直到缓冲区有空间或取消订阅，接收方不得访问当前映射
+	OverflowDisconnect                       // 断开订阅并关闭通道，不阻塞写操作
+)
+
+// SubscribeOption 订阅选项函数
+type SubscribeOption func(*subscribeOptions)
+
+// subscribeOptions 订阅选项
+type subscribeOptions struct {
+	buffer   int
+	overflow OverflowPolicy
+}
+
+// WithEventBuffer 设置订阅通道的缓冲区大小，默认64
+func WithEventBuffer(n int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.buffer = n
+	}
+}
+
+// WithOverflowPolicy 设置缓冲区已满时的处理策略，默认 OverflowDrop
+func WithOverflowPolicy(policy OverflowPolicy) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.overflow = policy
+	}
+}
+
+// Subscribe 订阅映射的变更事件，filter 为 nil 时订阅所有事件
+//
+// 事件在持有分片锁期间投递，同一个键的事件按发生顺序到达。filter 在分片锁内调用，不得访问当前映射。
+// 缓冲区已满时按 WithOverflowPolicy 处理：OverflowBlock 会在分片写锁内阻塞写操作，此时接收方不得访问当前映射
+// （包括 Get 等读操作），否则会死锁；订阅与取消订阅不受阻塞影响。调用 cancel 取消订阅并关闭通道，可重复调用；OverflowDisconnect 断开订阅时同样会关闭通道。
+func (m *Map[K, V]) Subscribe(filter func(event Event[K, V]) bool, options ...SubscribeOption) (<-chan Event[K, V], func()) {
+	sub := newSubscription[K, V](filter, options)
+
+	m.subsMu.Lock()
+	m.subs[sub] = struct{}{}
+	m.watched.Store(true)
+	m.subsMu.Unlock()
+
+	return sub.ch, func() { m.unsubscribe(sub) }
+}
+
+// WatchKey 订阅单个键的变更事件，映射被清空时同样会收到 EventClear
+//
+// 与 Subscribe 相同，但写操作只需投递给该键的订阅者，适合大量订阅者分别关注不同键的场景。
+func (m *Map[K, V]) WatchKey(key K, options ...SubscribeOption) (<-chan Event[K, V], func()) {
+	sub := newSubscription[K, V](nil, options)
+	sub.key, sub.keyed = key, true
+
+	m.subsMu.Lock()
+	subs, ok := m.keyed[key]
+	if !ok {
+		subs = make(map[*subscription[K, V]]struct{})
+		m.keyed[key] = subs
+	}
+	subs[sub] = struct{}{}
+	m.watched.Store(true)
+	m.subsMu.Unlock()
+
+	return sub.ch, func() { m.unsubscribe(sub) }
+}
+
+// ---------------------------------------------------------------------------------------------------------------------
+
+// subscription 订阅者
+type subscription[K cmp.Ordered, V any] struct {
+	ch       chan Event[K, V]
+	filter   func(event Event[K, V]) bool
+	overflow OverflowPolicy
+	done     chan struct{} // 取消或断开订阅时关闭，用于唤醒阻塞的投递
+	once     sync.Once
+	mu       sync.Mutex // 保证关闭ch时没有正在进行的投递
+
+	key   K    // 订阅的键，仅keyed为true时有效
+	keyed bool // 是否只订阅单个键
+}
+
+// newSubscription 创建订阅者
+func newSubscription[K cmp.Ordered, V any](filter func(event Event[K, V]) bool, options []SubscribeOption) *subscription[K, V] {
+	opts := subscribeOptions{buffer: 64}
+	for _, option := range options {
+		option(&opts)
+	}
+	return &subscription[K, V]{
+		ch:       make(chan Event[K, V], max(opts.buffer, 0)),
+		filter:   filter,
+		overflow: opts.overflow,
+		done:     make(chan struct{}),
+	}
+}
+
+// close 标记订阅已结束
+func (s *subscription[K, V]) close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+// closed 检查订阅是否已结束
+func (s *subscription[K, V]) closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// send 按溢出策略投递事件，缓冲区已满且需要断开订阅时返回false
+func (s *subscription[K, V]) send(event Event[K, V]) bool {
+	if s.filter != nil && !s.filter(event) {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed() {
+		return true
+	}
+
+	switch s.overflow {
+	case OverflowBlock:
+		select {
+		case s.ch <- event:
+		case <-s.done:
+		}
+	default:
+		select {
+		case s.ch <- event:
+		default:
+			if s.overflow == OverflowDisconnect {
+				s.close()
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// unsubscribe 取消订阅并关闭通道
+func (m *Map[K, V]) unsubscribe(sub *subscription[K, V]) {
+	// 先唤醒可能阻塞在该订阅者上的投递，再获取写锁
+	sub.close()
+
+	m.subsMu.Lock()
+	subs := m.subs
+	if sub.keyed {
+		subs = m.keyed[sub.key]
+	}
+	if _, ok := subs[sub]; ok {
+		delete(subs, sub)
+		if sub.keyed && len(subs) == 0 {
+			delete(m.keyed, sub.key)
+		}
+		// done 已关闭，阻塞的投递会立即返回并释放sub.mu
+		sub.mu.Lock()
+		close(sub.ch)
+		sub.mu.Unlock()
+		m.watched.Store(len(m.subs) > 0 || len(m.keyed) > 0)
+	}
+	m.subsMu.Unlock()
+}
+
+// publish 将分片锁内产生的变更投递给订阅者，调用方需持有分片写锁
+func (m *Map[K, V]) publish(changes []change[K, V]) {
+	events := make([]Event[K, V], 0, len(changes))
+	for _, c := range changes {
+		if c.kind != changeClear {
+			events = append(events, c.event())
+		}
+	}
+	m.broadcast(events...)
+}
+
+// delivery 待投递给某个订阅者的事件
+type delivery[K cmp.Ordered, V any] struct {
+	sub   *subscription[K, V]
+	event Event[K, V]
+}
+
+// broadcast 投递事件，并移除因缓冲区已满而断开的订阅者
+//
+// 只在读锁内复制需要投递的订阅者，投递在释放读锁后进行，阻塞的订阅者不会妨碍订阅、取消订阅及其他分片的投递。
+func (m *Map[K, V]) broadcast(events ...Event[K, V]) {
+	var deliveries []delivery[K, V]
+	m.subsMu.RLock()
+	for sub := range m.subs {
+		for _, event := range events {
+			deliveries = append(deliveries, delivery[K, V]{sub, event})
+		}
+	}
+	if len(m.keyed) > 0 {
+		for _, event := range events {
+			if event.Op == EventClear {
+				for _, subs := range m.keyed {
+					for sub := range subs {
+						deliveries = append(deliveries, delivery[K, V]{sub, event})
+					}
+				}
+				continue
+			}
+			for sub := range m.keyed[event.Key] {
+				deliveries = append(deliveries, delivery[K, V]{sub, event})
+			}
+		}
+	}
+	m.subsMu.RUnlock()
+
+	for _, d := range deliveries {
+		if !d.sub.send(d.event) {
+			m.unsubscribe(d.sub)
+		}
+	}
+}
+
+// event 将变更转换为事件
+func (c change[K, V]) event() Event[K, V] {
+	event := Event[K, V]{Key: c.key, OldValue: c.old, HasOld: c.replaced}
+	switch c.kind {
+	case changePut, changeLoad:
+		event.Op = EventPut
+		event.NewValue = c.value
+	case changeRemove:
+		event.Op = EventRemove
+	case changeExpire:
+		event.Op = EventExpire
+	case changeEvict:
+		event.Op = EventEvict
+	}
+	return event
+}
+
+// WaitFor 阻塞直到键存在，返回其值；ctx 结束时返回 ctx.Err()
+func (m *Map[K, V]) WaitFor(ctx context.Context, key K) (V, error) {
+	return m.WaitForFunc(ctx, key, nil)
+}
+
+// WaitForFunc 阻塞直到键存在且其值满足cond，返回该值；cond 为 nil 时只要求键存在
+//
+// 可用于按ID关联请求与响应等 goroutine 之间的汇合场景。cond 可能在分片锁外被多次调用。
+func (m *Map[K, V]) WaitForFunc(ctx context.Context, key K, cond func(value V) bool) (V, error) {
+	match := func(value V) bool {
+		return cond == nil || cond(value)
+	}
+
+	// 先订阅再检查当前值，避免错过检查与订阅之间的写入；
+	// 缓冲区只需容纳一个事件，被丢弃的事件由收到事件后重新读取当前值弥补
+	events, cancel := m.WatchKey(key, WithEventBuffer(1))
+	defer cancel()
+
+	for {
+		if value, ok := m.Get(key); ok && match(value) {
+			return value, nil
+		}
+
+		select {
+		case event := <-events:
+			if event.Op == EventPut && match(event.NewValue) {
+				return event.NewValue, nil
+			}
+		case <-ctx.Done():
+			var zero V
+			return zero, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package cmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receive 从通道读取一个事件，超时返回false
+func receive(ch <-chan Event[string, int]) (Event[string, int], bool) {
+	select {
+	case event, ok := <-ch:
+		return event, ok
+	case <-time.After(time.Second):
+		return Event[string, int]{}, false
+	}
+}
+
+// TestSubscribe 测试订阅变更事件
+func TestSubscribe(t *testing.T) {
+	clock := newFakeClock()
+	m := NewStringHashMap[int](WithClock(clock.Now))
+	events, cancel := m.Subscribe(nil)
+	defer cancel()
+
+	m.Put("a", 1)
+	m.Put("a", 2)
+	m.Remove("a")
+	m.PutWithTTL("b", 3, time.Second)
+	clock.Advance(time.Second)
+	m.Get("b")
+	m.Clear()
+
+	want := []Event[string, int]{
+		{Op: EventPut, Key: "a", NewValue: 1},
+		{Op: EventPut, Key: "a", OldValue: 1, NewValue: 2, HasOld: true},
+		{Op: EventRemove, Key: "a", OldValue: 2, HasOld: true},
+		{Op: EventPut, Key: "b", NewValue: 3},
+		{Op: EventExpire, Key: "b", OldValue: 3, HasOld: true},
+		{Op: EventClear},
+	}
+	for i, w := range want {
+		got, ok := receive(events)
+		if !ok {
+			t.Fatalf("Event %d not received", i)
+		}
+		if got != w {
+			t.Errorf("Event %d got %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+// TestSubscribeClearOrder 测试清空后的写入不会先于 EventClear 到达
+func TestSubscribeClearOrder(t *testing.T) {
+	var m *Map[string, int]
+	m = NewStringHashMap[int](WithShardCount(1), WithOnRemove(func(key string, value int) {
+		if value == 1 {
+			m.Put(key, 2)
+		}
+	}))
+	m.Put("k", 1)
+	events, cancel := m.Subscribe(nil)
+	defer cancel()
+
+	m.Clear()
+	want := []Event[string, int]{
+		{Op: EventClear},
+		{Op: EventPut, Key: "k", NewValue: 2},
+	}
+	for i, w := range want {
+		got, ok := receive(events)
+		if !ok {
+			t.Fatalf("Event %d not received", i)
+		}
+		if got != w {
+			t.Errorf("Event %d got %+v, want %+v", i, got, w)
+		}
+	}
+	if value, _ := m.Get("k"); value != 2 {
+		t.Errorf("k got %d, want 2", value)
+	}
+}
+
+// TestSubscribeFilter 测试事件过滤
+func TestSubscribeFilter(t *testing.T) {
+	m := NewStringHashMap[int](WithCapacity(1), WithShardCount(1))
+	events, cancel := m.Subscribe(func(event Event[string, int]) bool {
+		return event.Op == EventEvict
+	})
+	defer cancel()
+
+	m.Put("a", 1)
+	m.Put("b", 2)
+
+	got, ok := receive(events)
+	if !ok || got.Op != EventEvict || got.Key != "a" || got.OldValue != 1 {
+		t.Errorf("Filtered event got %+v, want evict a", got)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Unexpected event %+v", event)
+	default:
+	}
+}
+
+// TestSubscribeCancel 测试取消订阅
+func TestSubscribeCancel(t *testing.T) {
+	m := NewStringHashMap[int]()
+	events, cancel := m.Subscribe(nil)
+	cancel()
+	cancel()
+
+	if _, ok := <-events; ok {
+		t.Error("Channel should be closed after cancel")
+	}
+	m.Put("a", 1)
+	if m.watched.Load() {
+		t.Error("Map should not record changes without subscribers")
+	}
+}
+
+// TestSubscribeOverflow 测试缓冲区溢出策略
+func TestSubscribeOverflow(t *testing.T) {
+	t.Run("Drop", func(t *testing.T) {
+		m := NewStringHashMap[int]()
+		events, cancel := m.Subscribe(nil, WithEventBuffer(2))
+		defer cancel()
+
+		for i := 0; i < 10; i++ {
+			m.Put("a", i)
+		}
+		if len(events) != 2 {
+			t.Errorf("Buffer should hold 2 events, got %d", len(events))
+		}
+		if event := <-events; event.NewValue != 0 {
+			t.Errorf("Oldest event should be kept, got %v", event.NewValue)
+		}
+	})
+
+	t.Run("Disconnect", func(t *testing.T) {
+		m := NewStringHashMap[int]()
+		events, cancel := m.Subscribe(nil, WithEventBuffer(2), WithOverflowPolicy(OverflowDisconnect))
+		defer cancel()
+
+		for i := 0; i < 10; i++ {
+			m.Put("a", i)
+		}
+		count := 0
+		for range events {
+			count++
+		}
+		if count != 2 {
+			t.Errorf("Disconnected subscriber should receive 2 buffered events, got %d", count)
+		}
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		m := NewStringHashMap[int]()
+		events, cancel := m.Subscribe(nil, WithEventBuffer(1), WithOverflowPolicy(OverflowBlock))
+		defer cancel()
+
+		done := make(chan struct{})
+		go func() {
+			for i := 0; i < 10; i++ {
+				m.Put("a", i)
+			}
+			close(done)
+		}()
+
+		for i := 0; i < 10; i++ {
+			event, ok := receive(events)
+			if !ok || event.NewValue != i {
+				t.Fatalf("Event %d got %+v, want value %d", i, event, i)
+			}
+		}
+		<-done
+	})
+
+	t.Run("BlockCancel", func(t *testing.T) {
+		m := NewStringHashMap[int]()
+		_, cancel := m.Subscribe(nil, WithEventBuffer(1), WithOverflowPolicy(OverflowBlock))
+
+		done := make(chan struct{})
+		go func() {
+			m.Put("a", 1)
+			m.Put("a", 2)
+			close(done)
+		}()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Cancel should unblock writers")
+		}
+	})
+
+	t.Run("BlockSubscribe", func(t *testing.T) {
+		m := NewStringHashMap[int]()
+		_, cancel := m.Subscribe(nil, WithEventBuffer(1), WithOverflowPolicy(OverflowBlock))
+		defer cancel()
+
+		done := make(chan struct{})
+		go func() {
+			m.Put("a", 1)
+			m.Put("a", 2)
+			close(done)
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		subscribed := make(chan struct{})
+		go func() {
+			_, cancelOther := m.Subscribe(nil)
+			cancelOther()
+			_, cancelKey := m.WatchKey("b")
+			cancelKey()
+			close(subscribed)
+		}()
+		select {
+		case <-subscribed:
+		case <-time.After(time.Second):
+			t.Fatal("Blocked subscriber should not block Subscribe and cancel")
+		}
+
+		cancel()
+		<-done
+	})
+}
+
+// TestWatchKey 测试订阅单个键
+func TestWatchKey(t *testing.T) {
+	m := NewStringHashMap[int]()
+	events, cancel := m.WatchKey("a")
+	defer cancel()
+
+	m.Put("b", 1)
+	m.Put("a", 1)
+	m.Remove("b")
+	m.Remove("a")
+	m.Clear()
+
+	want := []EventOp{EventPut, EventRemove, EventClear}
+	for i, op := range want {
+		event, ok := receive(events)
+		if !ok || event.Op != op {
+			t.Fatalf("Event %d got %+v, want %v", i, event, op)
+		}
+		if op != EventClear && event.Key != "a" {
+			t.Errorf("Event %d key got %q, want a", i, event.Key)
+		}
+	}
+	select {
+	case event := <-events:
+		t.Errorf("Unexpected event %+v", event)
+	default:
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("Channel should be closed after cancel")
+	}
+	if len(m.keyed) != 0 {
+		t.Error("Cancel should remove key subscription")
+	}
+}
+
+// TestWaitFor 测试等待键出现
+func TestWaitFor(t *testing.T) {
+	m := NewStringHashMap[string]()
+
+	// 键已存在时直接返回
+	m.Put("ready", "ok")
+	if value, err := m.WaitFor(context.Background(), "ready"); err != nil || value != "ok" {
+		t.Errorf("WaitFor existing key got (%v, %v), want (ok, nil)", value, err)
+	}
+
+	// 请求/响应按ID汇合
+	const requests = 100
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			value, err := m.WaitFor(context.Background(), id)
+			if err == nil && value != "response-"+id {
+				err = fmt.Errorf("WaitFor %s got %s", id, value)
+			}
+			errs <- err
+		}(strconv.Itoa(i))
+	}
+	for i := 0; i < requests; i++ {
+		id := strconv.Itoa(i)
+		m.Put(id, "response-"+id)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestWaitForFunc 测试等待值满足条件
+func TestWaitForFunc(t *testing.T) {
+	m := NewStringHashMap[int]()
+	done := make(chan int)
+	go func() {
+		value, _ := m.WaitForFunc(context.Background(), "counter", func(value int) bool { return value >= 10 })
+		done <- value
+	}()
+
+	for i := 1; i <= 10; i++ {
+		m.Put("counter", i)
+	}
+	select {
+	case value := <-done:
+		if value != 10 {
+			t.Errorf("WaitForFunc got %d, want 10", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitForFunc did not return")
+	}
+}
+
+// TestWaitForCancel 测试等待时ctx结束
+func TestWaitForCancel(t *testing.T) {
+	m := NewStringHashMap[int]()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := m.WaitFor(ctx, "never"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitFor error got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if m.watched.Load() {
+		t.Error("WaitFor should unsubscribe after returning")
+	}
+}